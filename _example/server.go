@@ -27,6 +27,8 @@ func main() {
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/send-email", sail.SendEmailHandler)
+	mux.HandleFunc("/send-email/token", sail.SendEmailHandler)
+	mux.HandleFunc("/send-email/f/", sail.SendEmailHandler)
 	mux.Handle("/", fs)
 
 	log.Infof("Listening at http://localhost:%d", *port)
@@ -0,0 +1,51 @@
+package sail
+
+import (
+	"sync"
+
+	"github.com/demianbucik/sail/config"
+	"github.com/demianbucik/sail/mailer"
+)
+
+// assigner distributes submissions across assignees with smooth weighted round-robin,
+// which spreads the assignments evenly instead of assigning them in bursts.
+// The state is kept in memory, so each running instance distributes its own submissions.
+type assigner struct {
+	mu        sync.Mutex
+	assignees []config.Assignee
+	weights   []int
+	current   []int
+}
+
+func newAssigner(assignees []config.Assignee, mode config.AssignmentMode) *assigner {
+	weights := make([]int, len(assignees))
+	for i, assignee := range assignees {
+		weights[i] = 1
+		if mode == config.AssignmentWeighted && assignee.Weight > 0 {
+			weights[i] = assignee.Weight
+		}
+	}
+	return &assigner{
+		assignees: assignees,
+		weights:   weights,
+		current:   make([]int, len(assignees)),
+	}
+}
+
+func (a *assigner) next() mailer.Address {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	total, selected := 0, 0
+	for i, weight := range a.weights {
+		a.current[i] += weight
+		total += weight
+		if a.current[i] > a.current[selected] {
+			selected = i
+		}
+	}
+	a.current[selected] -= total
+
+	assignee := a.assignees[selected]
+	return mailer.NewAddress(assignee.Name, assignee.Email)
+}
@@ -0,0 +1,58 @@
+package sail
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/demianbucik/sail/config"
+)
+
+func TestAssigner(t *testing.T) {
+	assignees := []config.Assignee{
+		{Email: "a@example.com", Weight: 3},
+		{Email: "b@example.com", Weight: 1},
+		{Email: "c@example.com"},
+	}
+	tests := []struct {
+		name string
+		mode config.AssignmentMode
+		want string
+	}{
+		{"round-robin ignores the weights", config.AssignmentRoundRobin, "abcabc"},
+		// Smooth weighted round-robin spreads the assignments of heavier assignees instead of sending them in a row.
+		{"weighted", config.AssignmentWeighted, "abacaabacaabaca"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAssigner(assignees, tt.mode)
+			var got strings.Builder
+			for i := 0; i < len(tt.want); i++ {
+				email := a.next().Email
+				got.WriteString(email[:1])
+			}
+			if got.String() != tt.want {
+				t.Errorf("assignments = %s, want %s", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestAssigneeRecipient(t *testing.T) {
+	service, sent := newTestService(t, `
+CONFIRMATION_MODE: "off"
+ASSIGNEES: |
+  - {email: ann@mydomain.com, name: Ann}
+  - {email: tom@mydomain.com, name: Tom}
+`)
+	values := "name=Bob&email=bob@example.com&subject=Hi&message=Hello"
+	for _, want := range []string{"ann@mydomain.com", "tom@mydomain.com", "ann@mydomain.com"} {
+		if resp := submit(service, values); resp.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", resp.Code, resp.Body)
+		}
+		to := sent.last().To
+		if len(to) != 2 || to[0].Email != "bob.stone@gmail.com" || to[1].Email != want {
+			t.Errorf("email to %v, want the recipient and %s", to, want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package sail
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/demianbucik/sail/mailer"
+)
+
+// sniffedTypes are the media types http.DetectContentType recognizes, content it doesn't recognize
+// can't be any of them, so the type declared by the client is not trusted for these.
+var sniffedTypes = []string{
+	"text/*", "image/jpeg", "image/png", "image/gif", "image/webp", "image/bmp", "image/x-icon", "image/avif",
+	"image/svg+xml", "application/pdf", "application/postscript", "application/zip", "application/x-gzip",
+	"application/x-rar-compressed", "application/wasm", "application/ogg", "application/vnd.ms-fontobject",
+	"audio/*", "video/*", "font/*",
+}
+
+// readAttachments reads the files uploaded to the configured attachment fields and validates their sizes and types.
+func (service *sailService) readAttachments(request *http.Request) ([]mailer.Attachment, error) {
+	if !service.env.AttachmentsEnabled() || request.MultipartForm == nil {
+		return nil, nil
+	}
+
+	var attachments []mailer.Attachment
+	var totalSize int64
+	validationErr := ValidationError{}
+
+	for _, field := range service.env.AttachmentFields {
+		for _, fileHeader := range request.MultipartForm.File[field] {
+			// Browsers send an empty part for file inputs left empty.
+			if fileHeader.Filename == "" && fileHeader.Size == 0 {
+				continue
+			}
+
+			attachment, reason, err := service.readAttachment(fileHeader)
+			if err != nil {
+				return nil, err
+			}
+			if reason != "" {
+				validationErr[field] = reason
+				continue
+			}
+
+			totalSize += int64(len(attachment.Data))
+			attachments = append(attachments, *attachment)
+		}
+	}
+
+	if totalSize > service.env.MaxTotalSize() {
+		validationErr["attachments"] = fmt.Sprintf("total size exceeds %d bytes", service.env.MaxTotalSize())
+	}
+	if len(validationErr) > 0 {
+		return nil, validationErr
+	}
+	return attachments, nil
+}
+
+// readAttachment returns the attachment or the reason it was rejected.
+func (service *sailService) readAttachment(fileHeader *multipart.FileHeader) (*mailer.Attachment, string, error) {
+	filename := sanitizeFilename(fileHeader.Filename)
+	if fileHeader.Size > service.env.MaxFileSize() {
+		return nil, fmt.Sprintf("file '%s' exceeds %d bytes", filename, service.env.MaxFileSize()), nil
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Trust the content over the type declared by the client. The declared type is only used for content
+	// that can't be detected, and only when it's not a type that would have been detected.
+	mediaType := detectedType(http.DetectContentType(data))
+	if mediaType == "application/octet-stream" {
+		declared := detectedType(fileHeader.Header.Get("Content-Type"))
+		if declared == "application/octet-stream" {
+			declared = detectedType(mime.TypeByExtension(filepath.Ext(filename)))
+		}
+		if !isTypeAllowed(declared, sniffedTypes) {
+			mediaType = declared
+		}
+	}
+
+	if !isTypeAllowed(mediaType, service.env.AllowedTypes()) {
+		return nil, fmt.Sprintf("file '%s' has unsupported type '%s'", filename, mediaType), nil
+	}
+
+	return &mailer.Attachment{
+		Filename:    filename,
+		ContentType: mediaType,
+		Data:        data,
+	}, "", nil
+}
+
+// detectedType returns the media type of the content type, or "application/octet-stream" when there's none.
+func detectedType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return mediaType
+}
+
+func isTypeAllowed(mediaType string, allowedTypes []string) bool {
+	for _, allowed := range allowedTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func sanitizeFilename(filename string) string {
+	// Some browsers send the full client path.
+	filename = filepath.Base(strings.ReplaceAll(filename, `\`, "/"))
+	filename = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, filename)
+	if filename == "" || filename == "." || filename == "/" {
+		return "attachment"
+	}
+	return filename
+}
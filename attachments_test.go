@@ -0,0 +1,86 @@
+package sail
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/textproto"
+	"testing"
+
+	"github.com/demianbucik/sail/config"
+)
+
+// fileHeader uploads the content as a file with the declared content type.
+func fileHeader(t *testing.T, filename, contentType string, content []byte) *multipart.FileHeader {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="file"; filename="`+filename+`"`)
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	writer.Close()
+
+	form, err := multipart.NewReader(body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+	return form.File["file"][0]
+}
+
+func TestReadAttachment(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	executable := append([]byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff"), make([]byte, 64)...)
+	word := append([]byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1"), make([]byte, 64)...)
+
+	tests := []struct {
+		name        string
+		filename    string
+		declared    string
+		content     []byte
+		allowed     string
+		wantType    string
+		wantAllowed bool
+	}{
+		{"detected image", "photo.png", "image/png", png, "", "image/png", true},
+		{"detected over declared", "photo.pdf", "application/pdf", png, "", "image/png", true},
+		{"plain text", "notes.txt", "", []byte("Hello, Bob"), "", "text/plain", true},
+		{"executable declared as image", "photo.png", "image/png", executable, "", "application/octet-stream", false},
+		{"executable declared as text", "notes.txt", "text/plain", executable, "", "application/octet-stream", false},
+		{"executable with image extension", "photo.png", "", executable, "", "application/octet-stream", false},
+		{"undetected declared type", "cv.doc", "application/msword", word, "application/msword", "application/msword", true},
+		{"undetected type by extension", "cv.doc", "", word, "application/msword", "application/msword", true},
+		{"undetected type not allowed", "cv.doc", "application/msword", word, "", "application/msword", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := &config.Environ{}
+			if tt.allowed != "" {
+				if err := env.AttachmentAllowedTypes.UnmarshalText([]byte(tt.allowed)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			service := &sailService{env: env}
+
+			attachment, reason, err := service.readAttachment(fileHeader(t, tt.filename, tt.declared, tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantAllowed {
+				if reason != "" || attachment.ContentType != tt.wantType {
+					t.Errorf("readAttachment() = %+v, %q, want type %s", attachment, reason, tt.wantType)
+				}
+				return
+			}
+			if attachment != nil || reason != "file '"+tt.filename+"' has unsupported type '"+tt.wantType+"'" {
+				t.Errorf("readAttachment() = %+v, %q, want type %s rejected", attachment, reason, tt.wantType)
+			}
+		})
+	}
+}
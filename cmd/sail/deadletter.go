@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/demianbucik/sail"
+	"github.com/demianbucik/sail/config"
+	"github.com/demianbucik/sail/outbox"
+)
+
+func deadLetterCmd(args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing subcommand, use 'list', 'show' or 'replay'")
+	}
+
+	flags := flag.NewFlagSet("deadletter "+args[0], flag.ExitOnError)
+	envFilePath := flags.String("env", "env.yaml", "Path to YAML file with environment variables")
+	all := flags.Bool("all", false, "Replay all undelivered emails")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	env, err := config.ParseEnv(config.GetParseFromYAMLFunc(*envFilePath))
+	if err != nil {
+		return err
+	}
+	if !env.DeadLettersEnabled() {
+		return errors.New("DEAD_LETTER_DIR is not configured")
+	}
+	store, err := outbox.NewFileStore(env.DeadLetterDir)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		return listDeadLetters(store)
+	case "show":
+		if flags.NArg() != 1 {
+			return errors.New("expected a single ID")
+		}
+		return showDeadLetter(store, flags.Arg(0))
+	case "replay":
+		ids := flags.Args()
+		if *all == (len(ids) > 0) {
+			return errors.New("pass either IDs or -all")
+		}
+		return replayDeadLetters(env, store, ids)
+	default:
+		return fmt.Errorf("unknown subcommand '%s'", args[0])
+	}
+}
+
+func listDeadLetters(store outbox.Store) error {
+	entries, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tCREATED\tATTEMPTS\tTO\tSUBJECT\tLAST ERROR")
+	for _, entry := range entries {
+		var to []string
+		for _, addr := range entry.Message.To {
+			to = append(to, addr.Email)
+		}
+		var lastErr string
+		if len(entry.Errors) > 0 {
+			lastErr = entry.Errors[len(entry.Errors)-1].Error
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%s\t%s\t%s\n",
+			entry.ID,
+			entry.CreatedAt.Local().Format(time.DateTime),
+			entry.Attempts,
+			strings.Join(to, ", "),
+			truncate(entry.Message.Subject, 40),
+			truncate(lastErr, 60),
+		)
+	}
+	return writer.Flush()
+}
+
+func showDeadLetter(store outbox.Store, id string) error {
+	entry, err := store.Get(id)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entry)
+}
+
+func replayDeadLetters(env *config.Environ, store outbox.Store, ids []string) error {
+	emailClient, err := sail.NewMailer(env)
+	if err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		entries, err := store.List()
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			ids = append(ids, entry.ID)
+		}
+	}
+
+	failed := 0
+	for _, id := range ids {
+		if err = outbox.Replay(store, emailClient, id); err != nil {
+			failed++
+			fmt.Printf("%s: failed: %s\n", id, err)
+			continue
+		}
+		fmt.Printf("%s: sent\n", id)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d emails failed", failed, len(ids))
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-3]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: sail <command> [arguments]
+
+Commands:
+  deadletter list      List undelivered emails
+  deadletter show ID   Print an undelivered email with its error history
+  deadletter replay    Send undelivered emails again, pass IDs or -all
+  templates            Check the templates and render them with sample data
+
+Use "sail <command> [<subcommand>] -help" for more information.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "deadletter":
+		err = deadLetterCmd(os.Args[2:])
+	case "templates":
+		err = templatesCmd(os.Args[2:])
+	case "help", "-help", "-h":
+		fmt.Print(usage)
+		return
+	default:
+		err = fmt.Errorf("unknown command '%s'", os.Args[1])
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	htmltemplate "html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/demianbucik/sail"
+	"github.com/demianbucik/sail/config"
+)
+
+func templatesCmd(args []string) error {
+	flags := flag.NewFlagSet("templates", flag.ExitOnError)
+	envFilePath := flags.String("env", "env.yaml", "Path to YAML file with environment variables")
+	formId := flags.String("form", "", "ID of the form whose templates are rendered, the default form when empty")
+	dataPath := flags.String("data", "", "Path to JSON file with template variables replacing the sample values")
+	outDir := flags.String("out", "", "Write the rendered templates to this directory")
+	addr := flags.String("serve", "", "Serve a preview page on this address, for example localhost:8081")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	env, err := config.ParseEnv(config.GetParseFromYAMLFunc(*envFilePath))
+	if err != nil {
+		return err
+	}
+	var data map[string]any
+	if *dataPath != "" {
+		content, err := os.ReadFile(*dataPath)
+		if err != nil {
+			return err
+		}
+		if err = json.Unmarshal(content, &data); err != nil {
+			return fmt.Errorf("invalid data file: %w", err)
+		}
+	}
+
+	render := func() ([]sail.TemplatePreview, error) {
+		return sail.PreviewTemplates(env, *formId, data)
+	}
+	if *addr != "" {
+		return servePreviews(*addr, render)
+	}
+
+	previews, err := render()
+	if err != nil {
+		return err
+	}
+	if *outDir != "" {
+		if err = writePreviews(*outDir, previews); err != nil {
+			return err
+		}
+	}
+	return reportPreviews(previews)
+}
+
+func reportPreviews(previews []sail.TemplatePreview) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "TEMPLATE\tSTATUS\tDETAILS")
+	failed := 0
+	for _, preview := range previews {
+		status, details := "ok", ""
+		if preview.Err != nil {
+			failed++
+			status, details = "error", preview.Err.Error()
+		} else if preview.IsHeader {
+			details = preview.Body
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", preview.Name, status, details)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d templates failed", failed, len(previews))
+	}
+	return nil
+}
+
+// writePreviews writes the rendered template files, the headers are only reported.
+func writePreviews(dir string, previews []sail.TemplatePreview) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, preview := range previews {
+		if preview.Err != nil || preview.IsHeader {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(dir, preview.Name), []byte(preview.Body), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var previewIndex = htmltemplate.Must(htmltemplate.New("").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Sail templates</title></head>
+<body style="font-family: sans-serif;">
+<h1>Templates</h1>
+<table cellpadding="6">
+{{ range . }}
+<tr>
+  <td>{{ if or .Err .IsHeader }}{{ .Name }}{{ else }}<a href="/t/{{ .Name }}">{{ .Name }}</a>{{ end }}</td>
+  <td>{{ if .Err }}<span style="color: #c00;">{{ .Err }}</span>{{ else if .IsHeader }}{{ .Body }}{{ else }}ok{{ end }}</td>
+</tr>
+{{ end }}
+</table>
+</body>
+</html>
+`))
+
+// servePreviews renders the templates again on every request, so that changes to the files are shown after a refresh.
+func servePreviews(addr string, render func() ([]sail.TemplatePreview, error)) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/" {
+			http.NotFound(writer, request)
+			return
+		}
+		previews, err := render()
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err = previewIndex.Execute(writer, previews); err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+		}
+	})
+	mux.HandleFunc("/t/", func(writer http.ResponseWriter, request *http.Request) {
+		previews, err := render()
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		name := request.URL.Path[len("/t/"):]
+		for _, preview := range previews {
+			if preview.Name != name || preview.IsHeader {
+				continue
+			}
+			if preview.Err != nil {
+				http.Error(writer, preview.Err.Error(), http.StatusInternalServerError)
+				return
+			}
+			contentType := "text/plain; charset=utf-8"
+			if preview.IsHTML {
+				contentType = "text/html; charset=utf-8"
+			}
+			writer.Header().Set("Content-Type", contentType)
+			fmt.Fprint(writer, preview.Body)
+			return
+		}
+		http.NotFound(writer, request)
+	})
+
+	fmt.Printf("Serving template previews on http://%s\n", addr)
+	return http.ListenAndServe(addr, mux)
+}
@@ -2,35 +2,149 @@ package config
 
 import (
 	"fmt"
+	"mime"
 	"os"
 	"reflect"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/demianbucik/sail/mailer"
+	"github.com/demianbucik/sail/ratelimit"
 	"github.com/demianbucik/sail/utils"
 )
 
 type Environ struct {
-	envRequired  `yaml:",inline"`
-	envReCaptcha `yaml:",inline"`
+	envRequired     `yaml:",inline"`
+	envRecipients   `yaml:",inline"`
+	envHeaders      `yaml:",inline"`
+	envReCaptcha    `yaml:",inline"`
+	envTimeTrap     `yaml:",inline"`
+	envSpam         `yaml:",inline"`
+	envEmail        `yaml:",inline"`
+	envSMTP         `yaml:",inline"`
+	envMailgun      `yaml:",inline"`
+	envPostmark     `yaml:",inline"`
+	envSES          `yaml:",inline"`
+	envSendGrid     `yaml:",inline"`
+	envOutbox       `yaml:",inline"`
+	envConfirmation `yaml:",inline"`
+	envAttachments  `yaml:",inline"`
+	envTemplates    `yaml:",inline"`
+	envRateLimits   `yaml:",inline"`
 	// Optional fields
-	HoneypotField string `yaml:"HONEYPOT_FIELD"`
+	HoneypotField string       `yaml:"HONEYPOT_FIELD"`
+	FormFields    FormFields   `yaml:"FORM_FIELDS"`
+	Routes        RoutingRules `yaml:"ROUTES"`
+	// Additional forms served by the same deployment, see Form.
+	Forms Forms `yaml:"FORMS"`
 }
 
-func (env Environ) HoneypotCheckEnabled() bool {
-	return env.HoneypotField != ""
+type envRequired struct {
+	NoReplyEmail      string `yaml:"NOREPLY_EMAIL"`
+	NoReplyName       string `yaml:"NOREPLY_NAME"`
+	RecipientEmail    string `yaml:"RECIPIENT_EMAIL"`
+	RecipientName     string `yaml:"RECIPIENT_NAME"`
+	SuccessPage       string `yaml:"SUCCESS_PAGE"`
+	ErrorPage         string `yaml:"ERROR_PAGE"`
+	EmailTemplateFile string `yaml:"EMAIL_TEMPLATE_FILE"`
 }
 
-type envRequired struct {
-	SendGridApiKey           string `yaml:"SENDGRID_API_KEY"`
-	NoReplyEmail             string `yaml:"NOREPLY_EMAIL"`
-	NoReplyName              string `yaml:"NOREPLY_NAME"`
-	RecipientEmail           string `yaml:"RECIPIENT_EMAIL"`
-	RecipientName            string `yaml:"RECIPIENT_NAME"`
-	SuccessPage              string `yaml:"SUCCESS_PAGE"`
-	ErrorPage                string `yaml:"ERROR_PAGE"`
-	EmailTemplateFile        string `yaml:"EMAIL_TEMPLATE_FILE"`
-	ConfirmationTemplateFile string `yaml:"CONFIRMATION_TEMPLATE_FILE"`
+// envHeaders holds text/template templates of the subjects and sender names,
+// they are rendered with the same values and functions as the email templates.
+type envHeaders struct {
+	EmailSubject         string `yaml:"EMAIL_SUBJECT"`
+	EmailFromName        string `yaml:"EMAIL_FROM_NAME"`
+	ConfirmationSubject  string `yaml:"CONFIRMATION_SUBJECT"`
+	ConfirmationFromName string `yaml:"CONFIRMATION_FROM_NAME"`
+	// Localized confirmation subjects, CONFIRMATION_SUBJECT is used for other languages.
+	ConfirmationSubjects LocalizedText `yaml:"CONFIRMATION_SUBJECTS"`
+}
+
+type ConfirmationMode string
+
+const (
+	// ConfirmationRequired fails the whole submission when the confirmation can't be sent.
+	ConfirmationRequired ConfirmationMode = "required"
+	// ConfirmationOptional only logs, queues or stores a failed confirmation.
+	ConfirmationOptional ConfirmationMode = "optional"
+	ConfirmationOff      ConfirmationMode = "off"
+)
+
+type envConfirmation struct {
+	ConfirmationMode         ConfirmationMode `yaml:"CONFIRMATION_MODE"`
+	ConfirmationTemplateFile string           `yaml:"CONFIRMATION_TEMPLATE_FILE"`
+}
+
+// Confirmation returns the configured confirmation mode, confirmations are optional by default.
+func (env envConfirmation) Confirmation() ConfirmationMode {
+	if env.ConfirmationMode == "" {
+		return ConfirmationOptional
+	}
+	return env.ConfirmationMode
+}
+
+func (env envConfirmation) ConfirmationEnabled() bool {
+	return env.Confirmation() != ConfirmationOff
+}
+
+type envAttachments struct {
+	// Form fields that may contain file uploads, attachments are disabled when empty.
+	AttachmentFields       listAsStr     `yaml:"ATTACHMENT_FIELDS"`
+	AttachmentMaxFileSize  byteSizeAsStr `yaml:"ATTACHMENT_MAX_FILE_SIZE"`
+	AttachmentMaxTotalSize byteSizeAsStr `yaml:"ATTACHMENT_MAX_TOTAL_SIZE"`
+	AttachmentAllowedTypes listAsStr     `yaml:"ATTACHMENT_ALLOWED_TYPES"`
+}
+
+func (env envAttachments) AttachmentsEnabled() bool {
+	return len(env.AttachmentFields) > 0
+}
+
+// MaxFileSize returns the maximum size of a single attachment, 5 MB by default.
+func (env envAttachments) MaxFileSize() int64 {
+	if env.AttachmentMaxFileSize == 0 {
+		return 5 << 20
+	}
+	return int64(env.AttachmentMaxFileSize)
+}
+
+// MaxTotalSize returns the maximum size of all attachments combined, 10 MB by default.
+func (env envAttachments) MaxTotalSize() int64 {
+	if env.AttachmentMaxTotalSize == 0 {
+		return 10 << 20
+	}
+	return int64(env.AttachmentMaxTotalSize)
+}
+
+// AllowedTypes returns the accepted attachment media types, PDF documents, common images and plain text by default.
+// Types may use a wildcard subtype, such as "image/*".
+func (env envAttachments) AllowedTypes() []string {
+	if len(env.AttachmentAllowedTypes) == 0 {
+		return []string{"application/pdf", "image/jpeg", "image/png", "image/gif", "image/webp", "text/plain"}
+	}
+	return env.AttachmentAllowedTypes
+}
+
+type envTemplates struct {
+	// Templates in the directory replace the embedded templates with the same name.
+	TemplatesDir string `yaml:"TEMPLATES_DIR"`
+	// TemplatesReload parses the templates again when the files in TEMPLATES_DIR change, which is useful for development.
+	TemplatesReload boolAsStr `yaml:"TEMPLATES_RELOAD"`
+	// InlineCSS moves the style sheet rules of HTML templates into style attributes after rendering.
+	InlineCSS boolAsStr `yaml:"INLINE_CSS"`
+}
+
+// envRateLimits limits the submissions per client IP, submitted email address and form, empty limits are disabled.
+type envRateLimits struct {
+	RateLimitIP    ratelimit.Limit `yaml:"RATE_LIMIT_IP"`
+	RateLimitEmail ratelimit.Limit `yaml:"RATE_LIMIT_EMAIL"`
+	RateLimitForm  ratelimit.Limit `yaml:"RATE_LIMIT_FORM"`
+	// RateLimitPage is shown to rejected clients instead of the error page of the form.
+	RateLimitPage string `yaml:"RATE_LIMIT_PAGE"`
+	// TrustedProxies is the number of proxies in front of the function, such as Google's front end,
+	// that append the client IP to X-Forwarded-For. RATE_LIMIT_IP requires it.
+	TrustedProxies intAsStr `yaml:"TRUSTED_PROXIES"`
 }
 
 type envReCaptcha struct {
@@ -43,6 +157,144 @@ func (env envReCaptcha) ReCaptchaEnabled() bool {
 	return env.ReCaptchaVersion != "" && env.ReCaptchaVersion != "off"
 }
 
+// envTimeTrap rejects submissions sent too soon or too long after the form was rendered,
+// based on a signed token that the form gets when it's rendered.
+type envTimeTrap struct {
+	TimeTrapSecret      string        `yaml:"TIME_TRAP_SECRET"`
+	TimeTrapMinFillTime durationAsStr `yaml:"TIME_TRAP_MIN_FILL_TIME"`
+	TimeTrapMaxFillTime durationAsStr `yaml:"TIME_TRAP_MAX_FILL_TIME"`
+}
+
+func (env envTimeTrap) TimeTrapEnabled() bool {
+	return env.TimeTrapSecret != ""
+}
+
+// MinFillTime returns how long it takes at least to fill in the form, 3 seconds by default.
+func (env envTimeTrap) MinFillTime() time.Duration {
+	if env.TimeTrapMinFillTime == 0 {
+		return 3 * time.Second
+	}
+	return time.Duration(env.TimeTrapMinFillTime)
+}
+
+// MaxFillTime returns for how long the tokens are valid, 24 hours by default.
+func (env envTimeTrap) MaxFillTime() time.Duration {
+	if env.TimeTrapMaxFillTime == 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(env.TimeTrapMaxFillTime)
+}
+
+type envEmail struct {
+	// Ordered list of providers, the next provider is used when the previous one fails.
+	EmailProviders        listAsStr     `yaml:"EMAIL_PROVIDER"`
+	EmailFailureThreshold intAsStr      `yaml:"EMAIL_FAILURE_THRESHOLD"`
+	EmailCoolDown         durationAsStr `yaml:"EMAIL_COOLDOWN"`
+	SendGridApiKey        string        `yaml:"SENDGRID_API_KEY"`
+	// SendGridSandboxMode makes SendGrid validate the emails without delivering them.
+	SendGridSandboxMode boolAsStr `yaml:"SENDGRID_SANDBOX_MODE"`
+}
+
+// Providers returns the configured chain of email providers, SendGrid is used by default.
+func (env envEmail) Providers() []mailer.Provider {
+	if len(env.EmailProviders) == 0 {
+		return []mailer.Provider{mailer.SendGridProvider}
+	}
+	providers := make([]mailer.Provider, len(env.EmailProviders))
+	for i, provider := range env.EmailProviders {
+		providers[i] = mailer.Provider(provider)
+	}
+	return providers
+}
+
+// FailureThreshold returns the number of consecutive failures after which a provider is skipped.
+func (env envEmail) FailureThreshold() int {
+	if env.EmailFailureThreshold == 0 {
+		return 3
+	}
+	return int(env.EmailFailureThreshold)
+}
+
+// CoolDown returns for how long a failing provider is skipped.
+func (env envEmail) CoolDown() time.Duration {
+	if env.EmailCoolDown == 0 {
+		return time.Minute
+	}
+	return time.Duration(env.EmailCoolDown)
+}
+
+type envSMTP struct {
+	SMTPHost     string         `yaml:"SMTP_HOST"`
+	SMTPPort     intAsStr       `yaml:"SMTP_PORT"`
+	SMTPUsername string         `yaml:"SMTP_USERNAME"`
+	SMTPPassword string         `yaml:"SMTP_PASSWORD"`
+	SMTPTLSMode  mailer.TLSMode `yaml:"SMTP_TLS_MODE"`
+}
+
+// TLSMode returns the configured SMTP TLS mode, STARTTLS is used by default.
+func (env envSMTP) TLSMode() mailer.TLSMode {
+	if env.SMTPTLSMode == "" {
+		return mailer.TLSStartTLS
+	}
+	return env.SMTPTLSMode
+}
+
+// Port returns the configured SMTP port or the standard submission port for the TLS mode.
+func (env envSMTP) Port() int {
+	if env.SMTPPort != 0 {
+		return int(env.SMTPPort)
+	}
+	if env.TLSMode() == mailer.TLSImplicit {
+		return 465
+	}
+	return 587
+}
+
+type envMailgun struct {
+	MailgunApiKey  string `yaml:"MAILGUN_API_KEY"`
+	MailgunDomain  string `yaml:"MAILGUN_DOMAIN"`
+	MailgunBaseURL string `yaml:"MAILGUN_BASE_URL"`
+}
+
+type envPostmark struct {
+	PostmarkServerToken   string `yaml:"POSTMARK_SERVER_TOKEN"`
+	PostmarkMessageStream string `yaml:"POSTMARK_MESSAGE_STREAM"`
+	PostmarkBaseURL       string `yaml:"POSTMARK_BASE_URL"`
+}
+
+type envSES struct {
+	SESRegion          string `yaml:"SES_REGION"`
+	SESAccessKeyId     string `yaml:"SES_ACCESS_KEY_ID"`
+	SESSecretAccessKey string `yaml:"SES_SECRET_ACCESS_KEY"`
+	SESBaseURL         string `yaml:"SES_BASE_URL"`
+}
+
+type envOutbox struct {
+	OutboxDir              string        `yaml:"OUTBOX_DIR"`
+	OutboxRetryInterval    durationAsStr `yaml:"OUTBOX_RETRY_INTERVAL"`
+	OutboxMaxRetryInterval durationAsStr `yaml:"OUTBOX_MAX_RETRY_INTERVAL"`
+	OutboxMaxAttempts      intAsStr      `yaml:"OUTBOX_MAX_ATTEMPTS"`
+	DeadLetterDir          string        `yaml:"DEAD_LETTER_DIR"`
+}
+
+// OutboxEnabled reports whether emails are queued and delivered in the background.
+func (env envOutbox) OutboxEnabled() bool {
+	return env.OutboxDir != ""
+}
+
+// DeadLettersEnabled reports whether emails that could not be delivered are kept for a later replay.
+func (env envOutbox) DeadLettersEnabled() bool {
+	return env.DeadLetterDir != ""
+}
+
+// MaxAttempts returns the number of outbox delivery attempts before an email is moved to dead letters.
+func (env envOutbox) MaxAttempts() int {
+	if env.OutboxMaxAttempts == 0 {
+		return 10
+	}
+	return int(env.OutboxMaxAttempts)
+}
+
 func ParseEnv(parseFunc func(*Environ) error) (*Environ, error) {
 	env := &Environ{}
 	if err := parseFunc(env); err != nil {
@@ -51,25 +303,160 @@ func ParseEnv(parseFunc func(*Environ) error) (*Environ, error) {
 	if err := validate(env); err != nil {
 		return nil, err
 	}
+	if err := compile(env); err != nil {
+		return nil, err
+	}
 	return env, nil
 }
 
 func ParseFromOSEnv(env *Environ) error {
 	env.HoneypotField = os.Getenv("HONEYPOT_FIELD")
+	if err := env.FormFields.UnmarshalText([]byte(os.Getenv("FORM_FIELDS"))); err != nil {
+		return fmt.Errorf("invalid FORM_FIELDS value: %w", err)
+	}
+	if err := env.Routes.UnmarshalText([]byte(os.Getenv("ROUTES"))); err != nil {
+		return fmt.Errorf("invalid ROUTES value: %w", err)
+	}
+	if err := env.Forms.UnmarshalText([]byte(os.Getenv("FORMS"))); err != nil {
+		return fmt.Errorf("invalid FORMS value: %w", err)
+	}
+	if err := env.EmailProviders.UnmarshalText([]byte(os.Getenv("EMAIL_PROVIDER"))); err != nil {
+		return err
+	}
+	if err := env.EmailFailureThreshold.UnmarshalText([]byte(os.Getenv("EMAIL_FAILURE_THRESHOLD"))); err != nil {
+		return err
+	}
+	if err := env.EmailCoolDown.UnmarshalText([]byte(os.Getenv("EMAIL_COOLDOWN"))); err != nil {
+		return err
+	}
 	env.SendGridApiKey = os.Getenv("SENDGRID_API_KEY")
+	if err := env.SendGridSandboxMode.UnmarshalText([]byte(os.Getenv("SENDGRID_SANDBOX_MODE"))); err != nil {
+		return err
+	}
+	env.SendGridEmailTemplateId = os.Getenv("SENDGRID_EMAIL_TEMPLATE_ID")
+	env.SendGridConfirmationTemplateId = os.Getenv("SENDGRID_CONFIRMATION_TEMPLATE_ID")
+	if err := env.SendGridCategories.UnmarshalText([]byte(os.Getenv("SENDGRID_CATEGORIES"))); err != nil {
+		return err
+	}
+	if err := env.SendGridCustomArgs.UnmarshalText([]byte(os.Getenv("SENDGRID_CUSTOM_ARGS"))); err != nil {
+		return fmt.Errorf("invalid SENDGRID_CUSTOM_ARGS value: %w", err)
+	}
+	if err := env.SendGridAsmGroupId.UnmarshalText([]byte(os.Getenv("SENDGRID_ASM_GROUP_ID"))); err != nil {
+		return err
+	}
+	env.SMTPHost = os.Getenv("SMTP_HOST")
+	env.SMTPUsername = os.Getenv("SMTP_USERNAME")
+	env.SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	env.SMTPTLSMode = mailer.TLSMode(os.Getenv("SMTP_TLS_MODE"))
+	if err := env.SMTPPort.UnmarshalText([]byte(os.Getenv("SMTP_PORT"))); err != nil {
+		return err
+	}
+	env.MailgunApiKey = os.Getenv("MAILGUN_API_KEY")
+	env.MailgunDomain = os.Getenv("MAILGUN_DOMAIN")
+	env.MailgunBaseURL = os.Getenv("MAILGUN_BASE_URL")
+	env.PostmarkServerToken = os.Getenv("POSTMARK_SERVER_TOKEN")
+	env.PostmarkMessageStream = os.Getenv("POSTMARK_MESSAGE_STREAM")
+	env.PostmarkBaseURL = os.Getenv("POSTMARK_BASE_URL")
+	env.SESRegion = os.Getenv("SES_REGION")
+	env.SESAccessKeyId = os.Getenv("SES_ACCESS_KEY_ID")
+	env.SESSecretAccessKey = os.Getenv("SES_SECRET_ACCESS_KEY")
+	env.SESBaseURL = os.Getenv("SES_BASE_URL")
+	env.OutboxDir = os.Getenv("OUTBOX_DIR")
+	if err := env.OutboxRetryInterval.UnmarshalText([]byte(os.Getenv("OUTBOX_RETRY_INTERVAL"))); err != nil {
+		return err
+	}
+	if err := env.OutboxMaxRetryInterval.UnmarshalText([]byte(os.Getenv("OUTBOX_MAX_RETRY_INTERVAL"))); err != nil {
+		return err
+	}
+	env.DeadLetterDir = os.Getenv("DEAD_LETTER_DIR")
+	if err := env.OutboxMaxAttempts.UnmarshalText([]byte(os.Getenv("OUTBOX_MAX_ATTEMPTS"))); err != nil {
+		return err
+	}
+	if err := env.AttachmentFields.UnmarshalText([]byte(os.Getenv("ATTACHMENT_FIELDS"))); err != nil {
+		return err
+	}
+	if err := env.AttachmentMaxFileSize.UnmarshalText([]byte(os.Getenv("ATTACHMENT_MAX_FILE_SIZE"))); err != nil {
+		return err
+	}
+	if err := env.AttachmentMaxTotalSize.UnmarshalText([]byte(os.Getenv("ATTACHMENT_MAX_TOTAL_SIZE"))); err != nil {
+		return err
+	}
+	if err := env.AttachmentAllowedTypes.UnmarshalText([]byte(os.Getenv("ATTACHMENT_ALLOWED_TYPES"))); err != nil {
+		return err
+	}
+	env.TemplatesDir = os.Getenv("TEMPLATES_DIR")
+	if err := env.TemplatesReload.UnmarshalText([]byte(os.Getenv("TEMPLATES_RELOAD"))); err != nil {
+		return err
+	}
+	if err := env.InlineCSS.UnmarshalText([]byte(os.Getenv("INLINE_CSS"))); err != nil {
+		return err
+	}
+	if err := env.RateLimitIP.UnmarshalText([]byte(os.Getenv("RATE_LIMIT_IP"))); err != nil {
+		return err
+	}
+	if err := env.RateLimitEmail.UnmarshalText([]byte(os.Getenv("RATE_LIMIT_EMAIL"))); err != nil {
+		return err
+	}
+	if err := env.RateLimitForm.UnmarshalText([]byte(os.Getenv("RATE_LIMIT_FORM"))); err != nil {
+		return err
+	}
+	env.RateLimitPage = os.Getenv("RATE_LIMIT_PAGE")
+	if err := env.TrustedProxies.UnmarshalText([]byte(os.Getenv("TRUSTED_PROXIES"))); err != nil {
+		return err
+	}
 	env.NoReplyEmail = os.Getenv("NOREPLY_EMAIL")
 	env.NoReplyName = os.Getenv("NOREPLY_NAME")
 	env.RecipientEmail = os.Getenv("RECIPIENT_EMAIL")
 	env.RecipientName = os.Getenv("RECIPIENT_NAME")
+	if err := env.RecipientTo.UnmarshalText([]byte(os.Getenv("RECIPIENT_TO"))); err != nil {
+		return fmt.Errorf("invalid RECIPIENT_TO value: %w", err)
+	}
+	if err := env.RecipientCc.UnmarshalText([]byte(os.Getenv("RECIPIENT_CC"))); err != nil {
+		return fmt.Errorf("invalid RECIPIENT_CC value: %w", err)
+	}
+	if err := env.RecipientBcc.UnmarshalText([]byte(os.Getenv("RECIPIENT_BCC"))); err != nil {
+		return fmt.Errorf("invalid RECIPIENT_BCC value: %w", err)
+	}
+	if err := env.Assignees.UnmarshalText([]byte(os.Getenv("ASSIGNEES"))); err != nil {
+		return fmt.Errorf("invalid ASSIGNEES value: %w", err)
+	}
+	env.AssignmentMode = AssignmentMode(os.Getenv("ASSIGNMENT_MODE"))
 	env.SuccessPage = os.Getenv("SUCCESS_PAGE")
 	env.ErrorPage = os.Getenv("ERROR_PAGE")
 	env.EmailTemplateFile = os.Getenv("EMAIL_TEMPLATE_FILE")
+	env.EmailSubject = os.Getenv("EMAIL_SUBJECT")
+	env.EmailFromName = os.Getenv("EMAIL_FROM_NAME")
+	env.ConfirmationSubject = os.Getenv("CONFIRMATION_SUBJECT")
+	env.ConfirmationFromName = os.Getenv("CONFIRMATION_FROM_NAME")
+	if err := env.ConfirmationSubjects.UnmarshalText([]byte(os.Getenv("CONFIRMATION_SUBJECTS"))); err != nil {
+		return fmt.Errorf("invalid CONFIRMATION_SUBJECTS value: %w", err)
+	}
+	env.ConfirmationMode = ConfirmationMode(os.Getenv("CONFIRMATION_MODE"))
 	env.ConfirmationTemplateFile = os.Getenv("CONFIRMATION_TEMPLATE_FILE")
 	env.ReCaptchaSecretKey = os.Getenv("RECAPTCHA_SECRET_KEY")
 	env.ReCaptchaVersion = utils.RecaptchaVersion(os.Getenv("RECAPTCHA_VERSION"))
 	if err := env.ReCaptchaV3Threshold.UnmarshalText([]byte(os.Getenv("RECAPTCHA_V3_THRESHOLD"))); err != nil {
 		return err
 	}
+	env.TimeTrapSecret = os.Getenv("TIME_TRAP_SECRET")
+	if err := env.TimeTrapMinFillTime.UnmarshalText([]byte(os.Getenv("TIME_TRAP_MIN_FILL_TIME"))); err != nil {
+		return err
+	}
+	if err := env.TimeTrapMaxFillTime.UnmarshalText([]byte(os.Getenv("TIME_TRAP_MAX_FILL_TIME"))); err != nil {
+		return err
+	}
+	if err := env.SpamRules.UnmarshalText([]byte(os.Getenv("SPAM_RULES"))); err != nil {
+		return fmt.Errorf("invalid SPAM_RULES value: %w", err)
+	}
+	if err := env.SpamQuarantineScore.UnmarshalText([]byte(os.Getenv("SPAM_QUARANTINE_SCORE"))); err != nil {
+		return err
+	}
+	if err := env.SpamRejectScore.UnmarshalText([]byte(os.Getenv("SPAM_REJECT_SCORE"))); err != nil {
+		return err
+	}
+	if err := env.SpamQuarantineEmail.UnmarshalText([]byte(os.Getenv("SPAM_QUARANTINE_EMAIL"))); err != nil {
+		return fmt.Errorf("invalid SPAM_QUARANTINE_EMAIL value: %w", err)
+	}
 
 	return nil
 }
@@ -89,12 +476,58 @@ func GetParseFromYAMLFunc(filePath string) func(*Environ) error {
 }
 
 func validate(env *Environ) error {
-	if err := validateNonEmpty(&env.envRequired); err != nil {
+	if err := validateFormFields(env.FormFields); err != nil {
 		return err
 	}
-	if err := validateReCaptcha(&env.envReCaptcha); err != nil {
+	if err := validateForms(env); err != nil {
 		return err
 	}
+	if err := validateEmail(env); err != nil {
+		return err
+	}
+	if err := validateOutbox(&env.envOutbox); err != nil {
+		return err
+	}
+	if err := validateAttachments(&env.envAttachments); err != nil {
+		return err
+	}
+	if err := validateTemplates(&env.envTemplates); err != nil {
+		return err
+	}
+	if env.TrustedProxies < 0 {
+		return fmt.Errorf("invalid TRUSTED_PROXIES value '%d'", env.TrustedProxies)
+	}
+	// Behind a proxy, every client would share the bucket of the proxy's address.
+	if env.RateLimitIP.Enabled() && env.TrustedProxies == 0 {
+		return fmt.Errorf("RATE_LIMIT_IP requires TRUSTED_PROXIES, set it to '1' on Cloud Functions")
+	}
+	return nil
+}
+
+// compile prepares the patterns and regexes of the validated settings. The forms returned by Form share them,
+// settings that weren't compiled don't match any value.
+func compile(env *Environ) error {
+	if err := env.FormFields.compile(); err != nil {
+		return err
+	}
+	if err := env.Routes.compile(); err != nil {
+		return err
+	}
+	if err := env.SpamRules.Compile(); err != nil {
+		return fmt.Errorf("SPAM_RULES %w", err)
+	}
+	for i := range env.Forms {
+		form := &env.Forms[i]
+		if err := form.FormFields.compile(); err != nil {
+			return fmt.Errorf("FORMS form '%s': %w", form.ID, err)
+		}
+		if err := form.Routes.compile(); err != nil {
+			return fmt.Errorf("FORMS form '%s': %w", form.ID, err)
+		}
+		if err := form.SpamRules.Compile(); err != nil {
+			return fmt.Errorf("FORMS form '%s': SPAM_RULES %w", form.ID, err)
+		}
+	}
 	return nil
 }
 
@@ -141,3 +574,167 @@ func validateReCaptcha(env *envReCaptcha) error {
 
 	return nil
 }
+
+func validateTimeTrap(env *envTimeTrap) error {
+	if !env.TimeTrapEnabled() {
+		return nil
+	}
+	if len(env.TimeTrapSecret) < 16 {
+		return fmt.Errorf("TIME_TRAP_SECRET value should be at least 16 characters long")
+	}
+	if env.TimeTrapMinFillTime < 0 || env.TimeTrapMaxFillTime < 0 {
+		return fmt.Errorf("TIME_TRAP_MIN_FILL_TIME and TIME_TRAP_MAX_FILL_TIME values should not be negative")
+	}
+	if env.MinFillTime() >= env.MaxFillTime() {
+		return fmt.Errorf("TIME_TRAP_MIN_FILL_TIME value '%v' should be shorter than TIME_TRAP_MAX_FILL_TIME value '%v'",
+			env.MinFillTime(), env.MaxFillTime())
+	}
+	return nil
+}
+
+func validateEmail(env *Environ) error {
+	seen := make(map[mailer.Provider]bool)
+	for _, provider := range env.Providers() {
+		if seen[provider] {
+			return fmt.Errorf("EMAIL_PROVIDER value '%s' is listed more than once", provider)
+		}
+		seen[provider] = true
+
+		if err := validateProvider(env, provider); err != nil {
+			return err
+		}
+	}
+	if env.EmailFailureThreshold < 0 {
+		return fmt.Errorf("invalid EMAIL_FAILURE_THRESHOLD value '%d'", env.EmailFailureThreshold)
+	}
+	if env.EmailCoolDown < 0 {
+		return fmt.Errorf("invalid EMAIL_COOLDOWN value '%v'", time.Duration(env.EmailCoolDown))
+	}
+	if !env.sendGridEnabled() {
+		if env.SendGridSandboxMode {
+			return fmt.Errorf("SENDGRID_SANDBOX_MODE requires the 'sendgrid' EMAIL_PROVIDER")
+		}
+		for _, form := range env.AllForms() {
+			if !reflect.ValueOf(form.envSendGrid).IsZero() {
+				return fmt.Errorf("SENDGRID_* values require the 'sendgrid' EMAIL_PROVIDER")
+			}
+		}
+	}
+	return nil
+}
+
+func validateProvider(env *Environ, provider mailer.Provider) error {
+	switch provider {
+	case mailer.SendGridProvider:
+		if env.SendGridApiKey == "" {
+			return fmt.Errorf("SENDGRID_API_KEY value should not be empty")
+		}
+	case mailer.SMTPProvider:
+		return validateSMTP(&env.envSMTP)
+	case mailer.MailgunProvider:
+		return validateNonEmpty(&struct {
+			ApiKey string `yaml:"MAILGUN_API_KEY"`
+			Domain string `yaml:"MAILGUN_DOMAIN"`
+		}{env.MailgunApiKey, env.MailgunDomain})
+	case mailer.PostmarkProvider:
+		if env.PostmarkServerToken == "" {
+			return fmt.Errorf("POSTMARK_SERVER_TOKEN value should not be empty")
+		}
+	case mailer.SESProvider:
+		return validateNonEmpty(&struct {
+			Region          string `yaml:"SES_REGION"`
+			AccessKeyId     string `yaml:"SES_ACCESS_KEY_ID"`
+			SecretAccessKey string `yaml:"SES_SECRET_ACCESS_KEY"`
+		}{env.SESRegion, env.SESAccessKeyId, env.SESSecretAccessKey})
+	default:
+		return fmt.Errorf(
+			"invalid EMAIL_PROVIDER value '%s', valid options are 'sendgrid', 'smtp', 'mailgun', 'postmark' and 'ses'",
+			provider,
+		)
+	}
+
+	return nil
+}
+
+func validateSMTP(env *envSMTP) error {
+	if env.SMTPHost == "" {
+		return fmt.Errorf("SMTP_HOST value should not be empty")
+	}
+	if env.SMTPPort < 0 || env.SMTPPort > 65535 {
+		return fmt.Errorf("invalid SMTP_PORT value '%d'", env.SMTPPort)
+	}
+	if mode := env.TLSMode(); mode != mailer.TLSNone && mode != mailer.TLSStartTLS && mode != mailer.TLSImplicit {
+		return fmt.Errorf(
+			"invalid SMTP_TLS_MODE value '%s', valid options are 'none', 'starttls' and 'tls'",
+			env.SMTPTLSMode,
+		)
+	}
+	if env.SMTPUsername != "" && env.SMTPPassword == "" {
+		return fmt.Errorf("SMTP_PASSWORD value should not be empty when SMTP_USERNAME is set")
+	}
+
+	return nil
+}
+
+func validateOutbox(env *envOutbox) error {
+	if env.OutboxRetryInterval < 0 {
+		return fmt.Errorf("invalid OUTBOX_RETRY_INTERVAL value '%v'", time.Duration(env.OutboxRetryInterval))
+	}
+	if env.OutboxMaxRetryInterval != 0 && env.OutboxMaxRetryInterval < env.OutboxRetryInterval {
+		return fmt.Errorf("OUTBOX_MAX_RETRY_INTERVAL value should not be less than OUTBOX_RETRY_INTERVAL")
+	}
+	if env.OutboxMaxAttempts < 0 {
+		return fmt.Errorf("invalid OUTBOX_MAX_ATTEMPTS value '%d'", env.OutboxMaxAttempts)
+	}
+	if env.DeadLettersEnabled() && env.DeadLetterDir == env.OutboxDir {
+		return fmt.Errorf("DEAD_LETTER_DIR value should differ from OUTBOX_DIR")
+	}
+	return nil
+}
+
+func validateConfirmation(env *envConfirmation) error {
+	switch env.Confirmation() {
+	case ConfirmationRequired, ConfirmationOptional:
+		if env.ConfirmationTemplateFile == "" {
+			return fmt.Errorf("CONFIRMATION_TEMPLATE_FILE value should not be empty")
+		}
+	case ConfirmationOff:
+	default:
+		return fmt.Errorf(
+			"invalid CONFIRMATION_MODE value '%s', valid options are 'required', 'optional' and 'off'",
+			env.ConfirmationMode,
+		)
+	}
+	return nil
+}
+
+func validateAttachments(env *envAttachments) error {
+	if env.AttachmentMaxFileSize < 0 {
+		return fmt.Errorf("invalid ATTACHMENT_MAX_FILE_SIZE value '%d'", env.AttachmentMaxFileSize)
+	}
+	if env.AttachmentMaxTotalSize < 0 {
+		return fmt.Errorf("invalid ATTACHMENT_MAX_TOTAL_SIZE value '%d'", env.AttachmentMaxTotalSize)
+	}
+	if env.MaxFileSize() > env.MaxTotalSize() {
+		return fmt.Errorf("ATTACHMENT_MAX_FILE_SIZE value should not be greater than ATTACHMENT_MAX_TOTAL_SIZE")
+	}
+	for _, allowedType := range env.AllowedTypes() {
+		if _, _, err := mime.ParseMediaType(allowedType); err != nil || !strings.Contains(allowedType, "/") {
+			return fmt.Errorf("invalid ATTACHMENT_ALLOWED_TYPES value '%s'", allowedType)
+		}
+	}
+	return nil
+}
+
+func validateTemplates(env *envTemplates) error {
+	if env.TemplatesDir == "" {
+		if env.TemplatesReload {
+			return fmt.Errorf("TEMPLATES_DIR value should not be empty when TEMPLATES_RELOAD is enabled")
+		}
+		return nil
+	}
+	if info, err := os.Stat(env.TemplatesDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("invalid TEMPLATES_DIR value '%s', it should be an existing directory", env.TemplatesDir)
+	}
+	return nil
+}
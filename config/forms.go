@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+type FieldType string
+
+const (
+	FieldText     FieldType = "text"
+	FieldEmail    FieldType = "email"
+	FieldNumber   FieldType = "number"
+	FieldSelect   FieldType = "select"
+	FieldCheckbox FieldType = "checkbox"
+	FieldDate     FieldType = "date"
+)
+
+// FormField declares a single form field and how its value is validated.
+type FormField struct {
+	Name      string    `yaml:"name"`
+	Type      FieldType `yaml:"type"`
+	Required  bool      `yaml:"required"`
+	MinLength int       `yaml:"minLength"`
+	MaxLength int       `yaml:"maxLength"`
+	// Pattern is a regular expression the whole value has to match.
+	Pattern string `yaml:"pattern"`
+	// Options lists the valid values of select fields.
+	Options []string `yaml:"options"`
+	// Min and Max limit the values of number fields.
+	Min *float64 `yaml:"min"`
+	Max *float64 `yaml:"max"`
+
+	pattern *regexp.Regexp
+}
+
+// MatchesPattern reports whether the value matches the field's pattern, values always match fields without one.
+// Nothing matches a pattern that hasn't been compiled.
+func (field *FormField) MatchesPattern(value string) bool {
+	if field.Pattern == "" {
+		return true
+	}
+	return field.pattern != nil && field.pattern.MatchString(value)
+}
+
+// compilePattern compiles a field pattern, which has to match the whole value.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+// FormFields is a YAML list of fields, it can also be provided as a string, see yamlString.
+type FormFields []FormField
+
+// DefaultFormFields matches the fields of the original contact form.
+var DefaultFormFields = FormFields{
+	{Name: "name", Type: FieldText, Required: true},
+	{Name: "email", Type: FieldEmail, Required: true},
+	{Name: "subject", Type: FieldText, Required: true},
+	{Name: "message", Type: FieldText, Required: true},
+}
+
+func (fields *FormFields) UnmarshalText(text []byte) error {
+	return yamlString[[]FormField](func(list []FormField) { *fields = list }).UnmarshalText(text)
+}
+
+// Get returns the field with the given name.
+func (fields FormFields) Get(name string) (*FormField, bool) {
+	for i := range fields {
+		if fields[i].Name == name {
+			return &fields[i], true
+		}
+	}
+	return nil, false
+}
+
+func validateFormFields(fields FormFields) error {
+	seen := make(map[string]bool)
+	for i := range fields {
+		field := &fields[i]
+		if field.Name == "" {
+			return fmt.Errorf("FORM_FIELDS field %d has no name", i+1)
+		}
+		if seen[field.Name] {
+			return fmt.Errorf("FORM_FIELDS field '%s' is declared more than once", field.Name)
+		}
+		seen[field.Name] = true
+
+		if field.Type == "" {
+			field.Type = FieldText
+		}
+		switch field.Type {
+		case FieldText, FieldEmail, FieldNumber, FieldCheckbox, FieldDate:
+		case FieldSelect:
+			if len(field.Options) == 0 {
+				return fmt.Errorf("FORM_FIELDS select field '%s' has no options", field.Name)
+			}
+		default:
+			return fmt.Errorf(
+				"FORM_FIELDS field '%s' has invalid type '%s', valid options are "+
+					"'text', 'email', 'number', 'select', 'checkbox' and 'date'",
+				field.Name, field.Type,
+			)
+		}
+
+		if field.MinLength < 0 || field.MaxLength < 0 || field.MaxLength > 0 && field.MinLength > field.MaxLength {
+			return fmt.Errorf("FORM_FIELDS field '%s' has invalid length limits", field.Name)
+		}
+		if field.Min != nil && field.Max != nil && *field.Min > *field.Max {
+			return fmt.Errorf("FORM_FIELDS field '%s' has invalid min and max values", field.Name)
+		}
+		if field.Pattern != "" {
+			if _, err := compilePattern(field.Pattern); err != nil {
+				return fmt.Errorf("FORM_FIELDS field '%s' has invalid pattern: %w", field.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// compile prepares the patterns of validated fields.
+func (fields FormFields) compile() error {
+	for i := range fields {
+		field := &fields[i]
+		if field.Pattern == "" {
+			continue
+		}
+		pattern, err := compilePattern(field.Pattern)
+		if err != nil {
+			return fmt.Errorf("FORM_FIELDS field '%s' has invalid pattern: %w", field.Name, err)
+		}
+		field.pattern = pattern
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var languageTagPattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// LocalizedText maps lowercase language tags, such as "de" or "pt-br", to localized values.
+// It can also be provided as a string, see yamlString.
+type LocalizedText map[string]string
+
+func (text *LocalizedText) UnmarshalText(data []byte) error {
+	return yamlString[map[string]string](text.set).UnmarshalText(data)
+}
+
+func (text *LocalizedText) UnmarshalYAML(node *yaml.Node) error {
+	return yamlString[map[string]string](text.set).UnmarshalYAML(node)
+}
+
+func (text *LocalizedText) set(values map[string]string) {
+	*text = nil
+	for lang, value := range values {
+		if *text == nil {
+			*text = make(LocalizedText)
+		}
+		(*text)[strings.ToLower(lang)] = value
+	}
+}
+
+// IsLanguageTag reports whether tag is a lowercase language tag, such as "de" or "pt-br".
+func IsLanguageTag(tag string) bool {
+	return languageTagPattern.MatchString(tag)
+}
+
+func validateLocalizedText(name string, text LocalizedText) error {
+	for lang := range text {
+		if !IsLanguageTag(lang) {
+			return fmt.Errorf("invalid %s language '%s', use language tags such as 'de' or 'pt-BR'", name, lang)
+		}
+	}
+	return nil
+}
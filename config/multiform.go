@@ -0,0 +1,201 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+)
+
+var formIdPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Form holds the settings of a single form. Forms listed in FORMS only need to set the values
+// that differ from the top-level settings, the rest is inherited.
+type Form struct {
+	ID              string `yaml:"ID"`
+	envRequired     `yaml:",inline"`
+	envRecipients   `yaml:",inline"`
+	envHeaders      `yaml:",inline"`
+	envReCaptcha    `yaml:",inline"`
+	envTimeTrap     `yaml:",inline"`
+	envSpam         `yaml:",inline"`
+	envConfirmation `yaml:",inline"`
+	envSendGrid     `yaml:",inline"`
+	// HoneypotField is a pointer so that a form can disable an inherited check with an empty value.
+	HoneypotField *string    `yaml:"HONEYPOT_FIELD"`
+	FormFields    FormFields `yaml:"FORM_FIELDS"`
+	// Routes pick the recipient based on the submitted values, RECIPIENT_EMAIL is used when none match.
+	Routes RoutingRules `yaml:"ROUTES"`
+}
+
+func (form Form) HoneypotCheckEnabled() bool {
+	return form.HoneypotField != nil && *form.HoneypotField != ""
+}
+
+// Fields returns the declared form fields, or the name, email, subject and message fields by default.
+func (form Form) Fields() FormFields {
+	if len(form.FormFields) == 0 {
+		return DefaultFormFields
+	}
+	return form.FormFields
+}
+
+// Forms is a YAML list of forms, it can also be provided as a string, see yamlString.
+type Forms []Form
+
+func (forms *Forms) UnmarshalText(text []byte) error {
+	return yamlString[[]Form](func(list []Form) { *forms = list }).UnmarshalText(text)
+}
+
+// DefaultFormEnabled reports whether submissions without a form ID are accepted. When FORMS is set,
+// the top-level settings are only used as a form of their own if all the required values are set.
+func (env Environ) DefaultFormEnabled() bool {
+	return len(env.Forms) == 0 || validateNonEmpty(&env.envRequired) == nil
+}
+
+// Form returns the settings of the form with the given ID, the top-level settings are returned for an empty ID.
+func (env Environ) Form(id string) (*Form, bool) {
+	if id == "" {
+		if !env.DefaultFormEnabled() {
+			return nil, false
+		}
+		return env.defaultForm(), true
+	}
+	for _, form := range env.Forms {
+		if form.ID == id {
+			return form.inherit(env.defaultForm()), true
+		}
+	}
+	return nil, false
+}
+
+// AllForms returns the settings of every form that can be submitted, starting with the default form when it's enabled.
+func (env Environ) AllForms() []*Form {
+	var forms []*Form
+	if env.DefaultFormEnabled() {
+		forms = append(forms, env.defaultForm())
+	}
+	for _, form := range env.Forms {
+		forms = append(forms, form.inherit(env.defaultForm()))
+	}
+	return forms
+}
+
+func (env Environ) defaultForm() *Form {
+	honeypotField := env.HoneypotField
+	return &Form{
+		envRequired:     env.envRequired,
+		envRecipients:   env.envRecipients,
+		envHeaders:      env.envHeaders,
+		envReCaptcha:    env.envReCaptcha,
+		envTimeTrap:     env.envTimeTrap,
+		envSpam:         env.envSpam,
+		envConfirmation: env.envConfirmation,
+		envSendGrid:     env.envSendGrid,
+		HoneypotField:   &honeypotField,
+		FormFields:      env.FormFields,
+		Routes:          env.Routes,
+	}
+}
+
+// inherit returns a copy of the form with the unset values taken from the parent.
+func (form Form) inherit(parent *Form) *Form {
+	inheritZero(&form.envRequired, &parent.envRequired)
+	inheritZero(&form.envRecipients, &parent.envRecipients)
+	inheritZero(&form.envHeaders, &parent.envHeaders)
+	inheritZero(&form.envReCaptcha, &parent.envReCaptcha)
+	inheritZero(&form.envTimeTrap, &parent.envTimeTrap)
+	inheritZero(&form.envSpam, &parent.envSpam)
+	inheritZero(&form.envConfirmation, &parent.envConfirmation)
+	inheritZero(&form.envSendGrid, &parent.envSendGrid)
+	if form.HoneypotField == nil {
+		form.HoneypotField = parent.HoneypotField
+	}
+	if len(form.FormFields) == 0 {
+		form.FormFields = parent.FormFields
+	}
+	if len(form.Routes) == 0 {
+		form.Routes = parent.Routes
+	}
+	return &form
+}
+
+// inheritZero sets the zero fields of a struct to the values of the same fields of the parent.
+func inheritZero(envStruct, parent any) {
+	structVal := reflect.ValueOf(envStruct).Elem()
+	parentVal := reflect.ValueOf(parent).Elem()
+	for i := 0; i < structVal.NumField(); i++ {
+		if structVal.Field(i).IsZero() {
+			structVal.Field(i).Set(parentVal.Field(i))
+		}
+	}
+}
+
+func validateForms(env *Environ) error {
+	if len(env.Forms) == 0 {
+		return validateForm(env.defaultForm())
+	}
+
+	seen := make(map[string]bool)
+	for i, form := range env.Forms {
+		if !formIdPattern.MatchString(form.ID) {
+			return fmt.Errorf("invalid FORMS form %d ID value '%s', use letters, digits, '-' and '_'", i+1, form.ID)
+		}
+		// GET requests ending in "/token" fetch time-trap tokens.
+		if form.ID == "token" {
+			return fmt.Errorf("FORMS form %d ID value 'token' is reserved for the time-trap token URLs", i+1)
+		}
+		if seen[form.ID] {
+			return fmt.Errorf("FORMS form '%s' is declared more than once", form.ID)
+		}
+		seen[form.ID] = true
+	}
+
+	for _, form := range env.AllForms() {
+		if err := validateForm(form); err != nil {
+			if form.ID == "" {
+				return err
+			}
+			return fmt.Errorf("FORMS form '%s': %w", form.ID, err)
+		}
+	}
+	return nil
+}
+
+func validateForm(form *Form) error {
+	if err := validateNonEmpty(&form.envRequired); err != nil {
+		return err
+	}
+	if err := validateRecipients(&form.envRecipients); err != nil {
+		return err
+	}
+	if err := validateReCaptcha(&form.envReCaptcha); err != nil {
+		return err
+	}
+	if err := validateTimeTrap(&form.envTimeTrap); err != nil {
+		return err
+	}
+	if err := validateConfirmation(&form.envConfirmation); err != nil {
+		return err
+	}
+	if err := validateLocalizedText("CONFIRMATION_SUBJECTS", form.ConfirmationSubjects); err != nil {
+		return err
+	}
+	if err := validateSendGrid(&form.envSendGrid); err != nil {
+		return err
+	}
+	if err := validateFormFields(form.FormFields); err != nil {
+		return err
+	}
+	if err := validateRoutes(form.Routes, form.Fields()); err != nil {
+		return err
+	}
+	if err := validateSpam(&form.envSpam, form.Fields()); err != nil {
+		return err
+	}
+	if form.ConfirmationEnabled() {
+		if field, ok := form.Fields().Get("email"); !ok || field.Type != FieldEmail {
+			return fmt.Errorf("FORM_FIELDS should declare an 'email' field of type 'email' when confirmations are enabled")
+		}
+	}
+	return nil
+}
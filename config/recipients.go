@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+)
+
+type AssignmentMode string
+
+const (
+	// AssignmentRoundRobin assigns submissions to each assignee in turn.
+	AssignmentRoundRobin AssignmentMode = "round-robin"
+	// AssignmentWeighted assigns submissions in proportion to the assignee weights.
+	AssignmentWeighted AssignmentMode = "weighted"
+)
+
+// Assignee is a person submissions can be assigned to, the weight is only used in the weighted mode.
+type Assignee struct {
+	Email  string `yaml:"email"`
+	Name   string `yaml:"name"`
+	Weight int    `yaml:"weight"`
+}
+
+// Assignees is a YAML list of assignees, it can also be provided as a string, see yamlString.
+type Assignees []Assignee
+
+func (assignees *Assignees) UnmarshalText(text []byte) error {
+	return yamlString[[]Assignee](func(list []Assignee) { *assignees = list }).UnmarshalText(text)
+}
+
+type envRecipients struct {
+	// Additional recipients of the email, next to RECIPIENT_EMAIL.
+	RecipientTo  addressListAsStr `yaml:"RECIPIENT_TO"`
+	RecipientCc  addressListAsStr `yaml:"RECIPIENT_CC"`
+	RecipientBcc addressListAsStr `yaml:"RECIPIENT_BCC"`
+	// Each email is also sent to one of the assignees, assignment is disabled when empty.
+	Assignees      Assignees      `yaml:"ASSIGNEES"`
+	AssignmentMode AssignmentMode `yaml:"ASSIGNMENT_MODE"`
+}
+
+func (env envRecipients) AssignmentEnabled() bool {
+	return len(env.Assignees) > 0
+}
+
+// Assignment returns the configured assignment mode, round-robin is used by default.
+func (env envRecipients) Assignment() AssignmentMode {
+	if env.AssignmentMode == "" {
+		return AssignmentRoundRobin
+	}
+	return env.AssignmentMode
+}
+
+func validateRecipients(env *envRecipients) error {
+	switch env.Assignment() {
+	case AssignmentRoundRobin, AssignmentWeighted:
+	default:
+		return fmt.Errorf(
+			"invalid ASSIGNMENT_MODE value '%s', valid options are 'round-robin' and 'weighted'",
+			env.AssignmentMode,
+		)
+	}
+	for i, assignee := range env.Assignees {
+		if assignee.Email == "" {
+			return fmt.Errorf("ASSIGNEES assignee %d has no email", i+1)
+		}
+		if assignee.Weight < 0 {
+			return fmt.Errorf("ASSIGNEES assignee '%s' has invalid weight '%d'", assignee.Email, assignee.Weight)
+		}
+	}
+	return nil
+}
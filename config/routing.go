@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RoutingRule sends the email to a different recipient when a submitted field matches.
+// Each rule has a single condition, default rules match every submission.
+type RoutingRule struct {
+	Field    string `yaml:"field"`
+	Equals   string `yaml:"equals"`
+	Contains string `yaml:"contains"`
+	Regex    string `yaml:"regex"`
+	Default  bool   `yaml:"default"`
+
+	RecipientEmail string `yaml:"recipientEmail"`
+	RecipientName  string `yaml:"recipientName"`
+
+	regex *regexp.Regexp
+}
+
+// Matches reports whether the rule applies to the submitted fields.
+// Equality is exact, while contains ignores the case. A regex that hasn't been compiled doesn't match.
+func (rule *RoutingRule) Matches(fields map[string]string) bool {
+	if rule.Default {
+		return true
+	}
+	value := fields[rule.Field]
+	switch {
+	case rule.Equals != "":
+		return value == rule.Equals
+	case rule.Contains != "":
+		return strings.Contains(strings.ToLower(value), strings.ToLower(rule.Contains))
+	case rule.Regex != "":
+		return rule.regex != nil && rule.regex.MatchString(value)
+	}
+	return false
+}
+
+func (rule *RoutingRule) String() string {
+	var condition string
+	switch {
+	case rule.Default:
+		condition = "default"
+	case rule.Equals != "":
+		condition = fmt.Sprintf("'%s' equals '%s'", rule.Field, rule.Equals)
+	case rule.Contains != "":
+		condition = fmt.Sprintf("'%s' contains '%s'", rule.Field, rule.Contains)
+	default:
+		condition = fmt.Sprintf("'%s' matches '%s'", rule.Field, rule.Regex)
+	}
+	return fmt.Sprintf("%s -> %s", condition, rule.RecipientEmail)
+}
+
+// RoutingRules is a YAML list of rules, the first matching rule is used.
+// It can also be provided as a string, see yamlString.
+type RoutingRules []RoutingRule
+
+func (rules *RoutingRules) UnmarshalText(text []byte) error {
+	return yamlString[[]RoutingRule](func(list []RoutingRule) { *rules = list }).UnmarshalText(text)
+}
+
+// Route returns the first rule matching the submitted fields. When no rule matches,
+// a default rule with the form's RECIPIENT_EMAIL and RECIPIENT_NAME is returned.
+func (form Form) Route(fields map[string]string) *RoutingRule {
+	for i := range form.Routes {
+		if form.Routes[i].Matches(fields) {
+			return &form.Routes[i]
+		}
+	}
+	return &RoutingRule{Default: true, RecipientEmail: form.RecipientEmail, RecipientName: form.RecipientName}
+}
+
+func validateRoutes(rules RoutingRules, fields FormFields) error {
+	for i := range rules {
+		rule := &rules[i]
+		conditions := 0
+		for _, condition := range []string{rule.Equals, rule.Contains, rule.Regex} {
+			if condition != "" {
+				conditions++
+			}
+		}
+		if rule.Default {
+			if conditions > 0 || rule.Field != "" {
+				return fmt.Errorf("ROUTES default rule %d should not have a field or a condition", i+1)
+			}
+		} else {
+			if rule.Field == "" {
+				return fmt.Errorf("ROUTES rule %d has no field", i+1)
+			}
+			if _, ok := fields.Get(rule.Field); !ok {
+				return fmt.Errorf("ROUTES rule %d field '%s' is not declared in FORM_FIELDS", i+1, rule.Field)
+			}
+			if conditions != 1 {
+				return fmt.Errorf("ROUTES rule %d should have exactly one of 'equals', 'contains' and 'regex'", i+1)
+			}
+		}
+		if rule.RecipientEmail == "" {
+			return fmt.Errorf("ROUTES rule %d has no recipientEmail", i+1)
+		}
+		if rule.Regex != "" {
+			if _, err := regexp.Compile(rule.Regex); err != nil {
+				return fmt.Errorf("ROUTES rule %d has invalid regex: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// compile prepares the regexes of validated rules.
+func (rules RoutingRules) compile() error {
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Regex == "" {
+			continue
+		}
+		regex, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return fmt.Errorf("ROUTES rule %d has invalid regex: %w", i+1, err)
+		}
+		rule.regex = regex
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/demianbucik/sail/mailer"
+)
+
+// envSendGrid holds the SendGrid features of a form, the other providers ignore them.
+type envSendGrid struct {
+	// Dynamic templates are rendered by SendGrid, with the template values as dynamic_template_data.
+	SendGridEmailTemplateId        string    `yaml:"SENDGRID_EMAIL_TEMPLATE_ID"`
+	SendGridConfirmationTemplateId string    `yaml:"SENDGRID_CONFIRMATION_TEMPLATE_ID"`
+	SendGridCategories             listAsStr `yaml:"SENDGRID_CATEGORIES"`
+	SendGridCustomArgs             StringMap `yaml:"SENDGRID_CUSTOM_ARGS"`
+	// SendGridAsmGroupId is the unsubscribe group of the confirmations, the emails to the recipients have none.
+	SendGridAsmGroupId intAsStr `yaml:"SENDGRID_ASM_GROUP_ID"`
+}
+
+// StringMap is a YAML map of strings, it can also be provided as a string, see yamlString.
+type StringMap map[string]string
+
+func (m *StringMap) UnmarshalText(text []byte) error {
+	return yamlString[map[string]string](m.set).UnmarshalText(text)
+}
+
+func (m *StringMap) UnmarshalYAML(node *yaml.Node) error {
+	return yamlString[map[string]string](m.set).UnmarshalYAML(node)
+}
+
+func (m *StringMap) set(values map[string]string) {
+	*m = nil
+	if len(values) > 0 {
+		*m = values
+	}
+}
+
+func validateSendGrid(env *envSendGrid) error {
+	templateIds := map[string]string{
+		"SENDGRID_EMAIL_TEMPLATE_ID":        env.SendGridEmailTemplateId,
+		"SENDGRID_CONFIRMATION_TEMPLATE_ID": env.SendGridConfirmationTemplateId,
+	}
+	for name, id := range templateIds {
+		if id != "" && !strings.HasPrefix(id, "d-") {
+			return fmt.Errorf("invalid %s value '%s', dynamic template IDs start with 'd-'", name, id)
+		}
+	}
+	if len(env.SendGridCategories) > 10 {
+		return fmt.Errorf("SENDGRID_CATEGORIES should not have more than 10 categories")
+	}
+	for key := range env.SendGridCustomArgs {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("SENDGRID_CUSTOM_ARGS keys should not be empty")
+		}
+	}
+	if env.SendGridAsmGroupId < 0 {
+		return fmt.Errorf("invalid SENDGRID_ASM_GROUP_ID value '%d'", env.SendGridAsmGroupId)
+	}
+	return nil
+}
+
+// sendGridEnabled reports whether SendGrid is one of the email providers.
+func (env envEmail) sendGridEnabled() bool {
+	for _, provider := range env.Providers() {
+		if provider == mailer.SendGridProvider {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/demianbucik/sail/spam"
+)
+
+// envSpam scores the submissions with SPAM_RULES. Submissions scoring at least SPAM_REJECT_SCORE are rejected,
+// the ones scoring at least SPAM_QUARANTINE_SCORE are quarantined, zero thresholds are disabled.
+type envSpam struct {
+	SpamRules           spamRules  `yaml:"SPAM_RULES"`
+	SpamQuarantineScore floatAsStr `yaml:"SPAM_QUARANTINE_SCORE"`
+	SpamRejectScore     floatAsStr `yaml:"SPAM_REJECT_SCORE"`
+	// SpamQuarantineEmail receives the quarantined submissions instead of the recipients.
+	SpamQuarantineEmail addressAsStr `yaml:"SPAM_QUARANTINE_EMAIL"`
+}
+
+// spamRules is a YAML list of spam rules, it can also be provided as a string, see yamlString.
+type spamRules struct {
+	spam.Rules
+}
+
+func (rules *spamRules) UnmarshalText(text []byte) error {
+	return yamlString[spam.Rules](func(list spam.Rules) { rules.Rules = list }).UnmarshalText(text)
+}
+
+func (rules *spamRules) UnmarshalYAML(node *yaml.Node) error {
+	return yamlString[spam.Rules](func(list spam.Rules) { rules.Rules = list }).UnmarshalYAML(node)
+}
+
+func (env envSpam) SpamScoringEnabled() bool {
+	return len(env.SpamRules.Rules) > 0
+}
+
+func validateSpam(env *envSpam, fields FormFields) error {
+	if !env.SpamScoringEnabled() {
+		return nil
+	}
+	if err := env.SpamRules.Validate(); err != nil {
+		return fmt.Errorf("SPAM_RULES %w", err)
+	}
+	for i, rule := range env.SpamRules.Rules {
+		for _, name := range rule.CheckedFields() {
+			if _, ok := fields.Get(name); !ok {
+				return fmt.Errorf("SPAM_RULES rule %d field '%s' is not declared in FORM_FIELDS", i+1, name)
+			}
+		}
+	}
+
+	if env.SpamQuarantineScore < 0 || env.SpamRejectScore < 0 {
+		return fmt.Errorf("SPAM_QUARANTINE_SCORE and SPAM_REJECT_SCORE values should not be negative")
+	}
+	if env.SpamQuarantineScore == 0 && env.SpamRejectScore == 0 {
+		return fmt.Errorf("SPAM_RULES require SPAM_QUARANTINE_SCORE or SPAM_REJECT_SCORE")
+	}
+	if env.SpamQuarantineScore > 0 && env.SpamRejectScore > 0 && env.SpamRejectScore <= env.SpamQuarantineScore {
+		return fmt.Errorf("SPAM_REJECT_SCORE value '%v' should be higher than SPAM_QUARANTINE_SCORE value '%v'",
+			env.SpamRejectScore, env.SpamQuarantineScore)
+	}
+	return nil
+}
@@ -1,6 +1,15 @@
 package config
 
-import "strconv"
+import (
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/demianbucik/sail/mailer"
+)
 
 // GCP requires string values inside the YAML file with environment values.
 // For example, we need to use "0.25" instead of 0.25.
@@ -25,3 +34,182 @@ func (f *floatAsStr) UnmarshalText(text []byte) error {
 	*f = floatAsStr(value)
 	return nil
 }
+
+type boolAsStr bool
+
+func (b boolAsStr) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatBool(bool(b))), nil
+}
+
+func (b *boolAsStr) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*b = false
+		return nil
+	}
+	value, err := strconv.ParseBool(string(text))
+	if err != nil {
+		return err
+	}
+	*b = boolAsStr(value)
+	return nil
+}
+
+type intAsStr int
+
+func (i intAsStr) MarshalText() ([]byte, error) {
+	return []byte(strconv.Itoa(int(i))), nil
+}
+
+func (i *intAsStr) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*i = 0
+		return nil
+	}
+	value, err := strconv.Atoi(string(text))
+	if err != nil {
+		return err
+	}
+	*i = intAsStr(value)
+	return nil
+}
+
+// listAsStr is a comma separated list of values, for example "sendgrid, smtp".
+type listAsStr []string
+
+func (l listAsStr) MarshalText() ([]byte, error) {
+	return []byte(strings.Join(l, ",")), nil
+}
+
+func (l *listAsStr) UnmarshalText(text []byte) error {
+	*l = nil
+	for _, value := range strings.Split(string(text), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			*l = append(*l, value)
+		}
+	}
+	return nil
+}
+
+// durationAsStr is a duration such as "30s" or "5m".
+type durationAsStr time.Duration
+
+func (d durationAsStr) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *durationAsStr) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*d = 0
+		return nil
+	}
+	value, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = durationAsStr(value)
+	return nil
+}
+
+// byteSizeAsStr is a size in bytes, with an optional "KB" or "MB" suffix, for example "5MB".
+type byteSizeAsStr int64
+
+func (b byteSizeAsStr) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatInt(int64(b), 10)), nil
+}
+
+func (b *byteSizeAsStr) UnmarshalText(text []byte) error {
+	value := strings.ToUpper(strings.TrimSpace(string(text)))
+	if value == "" {
+		*b = 0
+		return nil
+	}
+	unit := int64(1)
+	for suffix, size := range map[string]int64{"KB": 1 << 10, "MB": 1 << 20} {
+		if strings.HasSuffix(value, suffix) {
+			value, unit = strings.TrimSpace(strings.TrimSuffix(value, suffix)), size
+		}
+	}
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return err
+	}
+	*b = byteSizeAsStr(size * unit)
+	return nil
+}
+
+// addressAsStr is a single email address, for example "Spam <spam@mydomain.com>".
+type addressAsStr mailer.Address
+
+func (a addressAsStr) MarshalText() ([]byte, error) {
+	if a.Email == "" {
+		return nil, nil
+	}
+	return []byte(mailer.Address(a).String()), nil
+}
+
+func (a *addressAsStr) UnmarshalText(text []byte) error {
+	*a = addressAsStr{}
+	if strings.TrimSpace(string(text)) == "" {
+		return nil
+	}
+	addr, err := mail.ParseAddress(string(text))
+	if err != nil {
+		return err
+	}
+	*a = addressAsStr(mailer.NewAddress(addr.Name, addr.Address))
+	return nil
+}
+
+// addressListAsStr is a comma separated list of email addresses, for example "Sales <sales@mydomain.com>, bob@mydomain.com".
+type addressListAsStr []mailer.Address
+
+func (l addressListAsStr) MarshalText() ([]byte, error) {
+	formatted := make([]string, len(l))
+	for i, addr := range l {
+		formatted[i] = addr.String()
+	}
+	return []byte(strings.Join(formatted, ", ")), nil
+}
+
+func (l *addressListAsStr) UnmarshalText(text []byte) error {
+	*l = nil
+	if strings.TrimSpace(string(text)) == "" {
+		return nil
+	}
+	addrs, err := mail.ParseAddressList(string(text))
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		*l = append(*l, mailer.NewAddress(addr.Name, addr.Address))
+	}
+	return nil
+}
+
+// yamlString decodes YAML lists and maps such as FORM_FIELDS. Because GCP only allows string environment values,
+// they can also be provided as a string containing the YAML. The value is decoded as T, which must not have
+// these methods itself, and passed to the function.
+// The YAML parser falls back to UnmarshalText for strings, so types decoded as they are from lists and maps
+// only need UnmarshalText.
+type yamlString[T any] func(T)
+
+func (set yamlString[T]) UnmarshalText(text []byte) error {
+	var value T
+	if err := yaml.Unmarshal(text, &value); err != nil {
+		return err
+	}
+	set(value)
+	return nil
+}
+
+func (set yamlString[T]) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return set.UnmarshalText([]byte(node.Value))
+	}
+	var value T
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	set(value)
+	return nil
+}
@@ -0,0 +1,170 @@
+package cssinline
+
+import (
+	"strings"
+)
+
+// rule is a top-level rule of a style sheet. Only rules with a selector list and declarations
+// can be inlined, at-rules such as media queries are kept as they were written.
+type rule struct {
+	text         string
+	selectors    string
+	declarations []declaration
+	atRule       bool
+}
+
+type declaration struct {
+	property  string
+	value     string
+	important bool
+}
+
+func (decl declaration) String() string {
+	if decl.important {
+		return decl.property + ": " + decl.value + " !important"
+	}
+	return decl.property + ": " + decl.value
+}
+
+// parseStyleSheet splits the style sheet into its top-level rules, comments are left out.
+func parseStyleSheet(css string) []rule {
+	css = stripComments(css)
+	var rules []rule
+	for pos := 0; pos < len(css); {
+		for pos < len(css) && isSpace(css[pos]) {
+			pos++
+		}
+		if pos >= len(css) {
+			break
+		}
+
+		start := pos
+		end := indexOutside(css, pos, "{;")
+		if end < 0 {
+			// An unterminated rule is kept, browsers ignore it anyway.
+			rules = append(rules, rule{text: strings.TrimSpace(css[start:]), atRule: true})
+			break
+		}
+		if css[end] == ';' {
+			rules = append(rules, rule{text: strings.TrimSpace(css[start : end+1]), atRule: true})
+			pos = end + 1
+			continue
+		}
+
+		blockEnd := matchingBrace(css, end)
+		if blockEnd < 0 {
+			blockEnd = len(css) - 1
+		}
+		r := rule{text: strings.TrimSpace(css[start : blockEnd+1])}
+		if css[start] == '@' {
+			r.atRule = true
+		} else {
+			r.selectors = strings.TrimSpace(css[start:end])
+			r.declarations = parseDeclarations(css[end+1 : blockEnd])
+		}
+		rules = append(rules, r)
+		pos = blockEnd + 1
+	}
+	return rules
+}
+
+// parseDeclarations parses the declarations of a rule or a style attribute, property names are lowercased.
+func parseDeclarations(block string) []declaration {
+	var declarations []declaration
+	for _, part := range splitOutside(block, ';') {
+		property, value, ok := strings.Cut(part, ":")
+		property = strings.ToLower(strings.TrimSpace(property))
+		value = strings.Join(strings.Fields(value), " ")
+		if !ok || property == "" || value == "" {
+			continue
+		}
+
+		important := false
+		if i := strings.LastIndexByte(value, '!'); i >= 0 && strings.EqualFold(strings.TrimSpace(value[i+1:]), "important") {
+			important = true
+			value = strings.TrimSpace(value[:i])
+		}
+		declarations = append(declarations, declaration{property: property, value: value, important: important})
+	}
+	return declarations
+}
+
+func stripComments(css string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(css, "/*")
+		if start < 0 {
+			b.WriteString(css)
+			return b.String()
+		}
+		b.WriteString(css[:start])
+		end := strings.Index(css[start+2:], "*/")
+		if end < 0 {
+			return b.String()
+		}
+		css = css[start+2+end+2:]
+	}
+}
+
+// indexOutside returns the index of the first of the chars that isn't quoted or in parentheses, or -1.
+func indexOutside(s string, pos int, chars string) int {
+	var quote byte
+	depth := 0
+	for i := pos; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth > 0 {
+				depth--
+			}
+		case depth == 0 && strings.IndexByte(chars, c) >= 0:
+			return i
+		}
+	}
+	return -1
+}
+
+// matchingBrace returns the index of the brace closing the block opened at pos, or -1.
+func matchingBrace(s string, pos int) int {
+	depth := 0
+	for i := pos; i >= 0 && i < len(s); {
+		i = indexOutside(s, i, "{}")
+		if i < 0 {
+			return -1
+		}
+		if s[i] == '{' {
+			depth++
+		} else if depth--; depth == 0 {
+			return i
+		}
+		i++
+	}
+	return -1
+}
+
+// splitOutside splits the string at every separator that isn't quoted or in parentheses.
+func splitOutside(s string, sep byte) []string {
+	var parts []string
+	for {
+		i := indexOutside(s, 0, string(sep))
+		if i < 0 {
+			return append(parts, s)
+		}
+		parts = append(parts, s[:i])
+		s = s[i+1:]
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
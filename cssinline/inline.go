@@ -0,0 +1,213 @@
+// Package cssinline moves the rules of an HTML document's style sheets into the style attributes of
+// the elements they match, because several email clients ignore or strip style elements.
+package cssinline
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// inlineSpecificity puts declarations of style attributes above any selector.
+var inlineSpecificity = specificity{math.MaxInt, 0, 0}
+
+type inlineRule struct {
+	selectors    []selector
+	declarations []declaration
+	order        int
+	matched      bool
+}
+
+// styleRule is a rule of a style element, it's inlined when it has selectors and matches an element.
+type styleRule struct {
+	text   string
+	inline *inlineRule
+}
+
+type matchedDeclaration struct {
+	declaration
+	specificity specificity
+	order       int
+}
+
+// Inline returns the document with the rules of its style elements inlined. Rules that can't be inlined,
+// such as media queries, @font-face and selectors with pseudo-classes, stay in the style elements, and so
+// do rules that don't match any element, because they might target elements added by the email client.
+// Style elements with nothing left are removed, and style elements for media other than "all" and "screen"
+// are left as they are. Documents without rules to inline are returned unchanged.
+func Inline(document string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return "", err
+	}
+
+	styles := styleElements(doc)
+	styleRules := make([][]styleRule, len(styles))
+	var rules []*inlineRule
+	order := 0
+	for i, style := range styles {
+		for _, r := range parseStyleSheet(textContent(style)) {
+			sr := styleRule{text: r.text}
+			if selectors, ok := parseSelectors(r.selectors); !r.atRule && ok {
+				sr.inline = &inlineRule{selectors: selectors, declarations: r.declarations, order: order}
+				rules = append(rules, sr.inline)
+				order += len(r.declarations)
+			}
+			styleRules[i] = append(styleRules[i], sr)
+		}
+	}
+
+	inlined := false
+	walkBody(doc, func(node *html.Node) {
+		if applyRules(node, rules) {
+			inlined = true
+		}
+	})
+	if !inlined {
+		return document, nil
+	}
+
+	for i, style := range styles {
+		var kept []string
+		for _, sr := range styleRules[i] {
+			if sr.inline == nil || !sr.inline.matched {
+				kept = append(kept, sr.text)
+			}
+		}
+		if len(kept) == 0 {
+			style.Parent.RemoveChild(style)
+			continue
+		}
+		for child := style.FirstChild; child != nil; child = style.FirstChild {
+			style.RemoveChild(child)
+		}
+		style.AppendChild(&html.Node{Type: html.TextNode, Data: "\n" + strings.Join(kept, "\n") + "\n"})
+	}
+
+	buf := &bytes.Buffer{}
+	if err = html.Render(buf, doc); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// applyRules sets the style attribute of the element to the cascaded declarations of the matching rules.
+// Important declarations win over the rest, then the declarations with a higher specificity or a later position.
+// It reports whether any rule matched.
+func applyRules(node *html.Node, rules []*inlineRule) bool {
+	var matched []matchedDeclaration
+	for _, r := range rules {
+		spec, ok := matchingSpecificity(node, r.selectors)
+		if !ok {
+			continue
+		}
+		r.matched = true
+		for i, decl := range r.declarations {
+			matched = append(matched, matchedDeclaration{declaration: decl, specificity: spec, order: r.order + i})
+		}
+	}
+	if len(matched) == 0 {
+		return false
+	}
+
+	styleIndex := -1
+	for i, attr := range node.Attr {
+		if attr.Namespace == "" && attr.Key == "style" {
+			styleIndex = i
+			for _, decl := range parseDeclarations(attr.Val) {
+				matched = append(matched, matchedDeclaration{declaration: decl, specificity: inlineSpecificity})
+			}
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if a.important != b.important {
+			return b.important
+		}
+		if a.specificity != b.specificity {
+			return a.specificity.less(b.specificity)
+		}
+		return a.order < b.order
+	})
+
+	var properties []string
+	values := make(map[string]declaration)
+	for _, m := range matched {
+		if _, ok := values[m.property]; !ok {
+			properties = append(properties, m.property)
+		}
+		values[m.property] = m.declaration
+	}
+	declarations := make([]string, len(properties))
+	for i, property := range properties {
+		declarations[i] = values[property].String()
+	}
+
+	style := strings.Join(declarations, "; ")
+	if styleIndex >= 0 {
+		node.Attr[styleIndex].Val = style
+	} else {
+		node.Attr = append(node.Attr, html.Attribute{Key: "style", Val: style})
+	}
+	return true
+}
+
+func matchingSpecificity(node *html.Node, selectors []selector) (specificity, bool) {
+	var spec specificity
+	found := false
+	for _, sel := range selectors {
+		if sel.matches(node) && (!found || spec.less(sel.specificity)) {
+			spec, found = sel.specificity, true
+		}
+	}
+	return spec, found
+}
+
+func styleElements(doc *html.Node) []*html.Node {
+	var styles []*html.Node
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.Data == "style" {
+			media := strings.TrimSpace(strings.ToLower(attribute(node, "media")))
+			if media == "" || media == "all" || media == "screen" {
+				styles = append(styles, node)
+			}
+			return
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+	return styles
+}
+
+// walkBody calls fn for every element of the document body, including the body itself.
+func walkBody(node *html.Node, fn func(node *html.Node)) {
+	if node.Type == html.ElementNode {
+		switch node.Data {
+		case "head", "style", "script", "template":
+			return
+		case "html":
+		default:
+			fn(node)
+		}
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		walkBody(child, fn)
+	}
+}
+
+func textContent(node *html.Node) string {
+	var b strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.TextNode {
+			b.WriteString(child.Data)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,169 @@
+package cssinline
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// styles returns the style attributes of the elements with an id, keyed by the id.
+func styles(t *testing.T, document string) map[string]string {
+	t.Helper()
+	doc, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make(map[string]string)
+	walkBody(doc, func(node *html.Node) {
+		if id := attribute(node, "id"); id != "" {
+			found[id] = attribute(node, "style")
+		}
+	})
+	return found
+}
+
+func TestInline(t *testing.T) {
+	tests := []struct {
+		name  string
+		css   string
+		body  string
+		wants map[string]string
+	}{
+		{
+			name: "specificity",
+			css:  "#a { color: green } .a { color: blue } p { color: red }",
+			body: `<p id="a" class="a">a</p><p id="b" class="a">b</p><p id="c">c</p>`,
+			wants: map[string]string{
+				"a": "color: green",
+				"b": "color: blue",
+				"c": "color: red",
+			},
+		},
+		{
+			name: "later rule with the same specificity",
+			css:  ".a { color: red } .b { color: blue } body p.a { color: green } p.b { color: black }",
+			body: `<p id="a" class="a b">a</p>`,
+			wants: map[string]string{
+				"a": "color: green",
+			},
+		},
+		{
+			name: "descendant and child combinators",
+			css:  "div p { color: red } div > p { margin: 0 } ul li + li { padding: 1px } ul li ~ li { border: 0 }",
+			body: `<div><p id="child">a</p><span><p id="descendant">b</p></span></div><p id="outside">c</p>` +
+				`<ul><li id="first">1</li><li id="second">2</li><li id="third">3</li></ul>`,
+			wants: map[string]string{
+				"child":      "color: red; margin: 0",
+				"descendant": "color: red",
+				"outside":    "",
+				"first":      "",
+				"second":     "padding: 1px; border: 0",
+				"third":      "padding: 1px; border: 0",
+			},
+		},
+		{
+			name: "attribute selectors",
+			css: `[data-x] { a: 1 } [type=text] { b: 2 } [class~="b"] { c: 3 } [lang|=en] { d: 4 } ` +
+				`[href^="https:"] { e: 5 } [href$='.pdf'] { f: 6 } [href*=example] { g: 7 }`,
+			body: `<span id="has" data-x="">a</span><input id="equals" type="text"><input id="other" type="textarea">` +
+				`<p id="word" class="a b">b</p><p id="prefix" class="ab">c</p><p id="lang" lang="en-GB">d</p>` +
+				`<a id="link" href="https://example.com/a.pdf">e</a><a id="plain" href="http://test.org/">f</a>`,
+			wants: map[string]string{
+				"has":    "a: 1",
+				"equals": "b: 2",
+				"other":  "",
+				"word":   "c: 3",
+				"prefix": "",
+				"lang":   "d: 4",
+				"link":   "e: 5; f: 6; g: 7",
+				"plain":  "",
+			},
+		},
+		{
+			name: "important and inline styles",
+			css:  "p { color: red !important; margin: 0 } #b { color: blue !important }",
+			body: `<p id="a" style="color: green; margin: 1px">a</p><p id="b" style="color: green !important">b</p>` +
+				`<p id="c" style="padding: 0">c</p>`,
+			wants: map[string]string{
+				"a": "margin: 1px; color: red !important",
+				"b": "margin: 0; color: green !important",
+				"c": "margin: 0; padding: 0; color: red !important",
+			},
+		},
+		{
+			name: "quoted values",
+			css:  `p { font-family: "a;b}" , serif; background: url('x;y}.png'); color: red }`,
+			body: `<p id="a">a</p>`,
+			wants: map[string]string{
+				"a": `font-family: "a;b}" , serif; background: url('x;y}.png'); color: red`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document := "<html><head><style>" + tt.css + "</style></head><body>" + tt.body + "</body></html>"
+			got, err := Inline(document)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := styles(t, got)
+			for id, want := range tt.wants {
+				if found[id] != want {
+					t.Errorf("style of #%s = %q, want %q", id, found[id], want)
+				}
+			}
+		})
+	}
+}
+
+func TestInlineKeepsRules(t *testing.T) {
+	css := `@media (max-width: 600px) { p { color: blue } } a:hover { color: red } p::first-line { color: red }
+@font-face { font-family: "x" } .missing { color: red } p { margin: 0 }`
+	document := "<html><head><style>" + css + "</style></head><body><p id=\"a\">a</p><a href=\"/\">b</a></body></html>"
+	got, err := Inline(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if style := styles(t, got)["a"]; style != "margin: 0" {
+		t.Errorf("style of #a = %q, want %q", style, "margin: 0")
+	}
+	for _, kept := range []string{
+		"@media (max-width: 600px) { p { color: blue } }",
+		"a:hover { color: red }",
+		"p::first-line { color: red }",
+		`@font-face { font-family: "x" }`,
+		".missing { color: red }",
+	} {
+		if !strings.Contains(got, kept) {
+			t.Errorf("rule %q should be kept in\n%s", kept, got)
+		}
+	}
+	if strings.Contains(got, "p { margin: 0 }") {
+		t.Errorf("inlined rule should be removed from\n%s", got)
+	}
+}
+
+func TestInlineUnchanged(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+	}{
+		{"no style elements", `<html><body><p style="color: red">a</p></body></html>`},
+		{"only media queries", `<html><head><style>@media print { p { color: red } }</style></head><body><p>a</p></body></html>`},
+		{"print style sheet", `<html><head><style media="print">p { color: red }</style></head><body><p>a</p></body></html>`},
+		{"no matching elements", `<html><head><style>.a { color: red }</style></head><body><p>a</p></body></html>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Inline(tt.document)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.document {
+				t.Errorf("Inline() = %s, want the document unchanged", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package cssinline
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// selector is a complex selector, compound selectors joined by combinators. Pseudo-classes and
+// pseudo-elements depend on the state of the document in the email client, so they're not supported.
+type selector struct {
+	// compounds are in reverse order, the compound matching the element itself comes first.
+	compounds   []compound
+	specificity specificity
+}
+
+// compound matches a single element, the combinator relates it to the previous compound in the document.
+type compound struct {
+	combinator byte
+	tag        string
+	id         string
+	classes    []string
+	attrs      []attrMatcher
+}
+
+type attrMatcher struct {
+	name     string
+	operator string
+	value    string
+}
+
+type specificity [3]int
+
+func (s specificity) less(other specificity) bool {
+	for i := range s {
+		if s[i] != other[i] {
+			return s[i] < other[i]
+		}
+	}
+	return false
+}
+
+// parseSelectors parses a selector list, it fails when any of the selectors isn't supported.
+func parseSelectors(text string) ([]selector, bool) {
+	var selectors []selector
+	for _, part := range splitOutside(text, ',') {
+		sel, ok := parseSelector(strings.TrimSpace(part))
+		if !ok {
+			return nil, false
+		}
+		selectors = append(selectors, sel)
+	}
+	return selectors, len(selectors) > 0
+}
+
+func parseSelector(text string) (selector, bool) {
+	var sel selector
+	combinator := byte(' ')
+	for pos := 0; pos < len(text); {
+		var comp compound
+		var ok bool
+		comp, pos, ok = parseCompound(text, pos)
+		if !ok {
+			return selector{}, false
+		}
+		comp.combinator = combinator
+		sel.compounds = append([]compound{comp}, sel.compounds...)
+		sel.specificity = addSpecificity(sel.specificity, comp)
+
+		// Whitespace alone is the descendant combinator, otherwise it surrounds an explicit one.
+		start := pos
+		for pos < len(text) && isSpace(text[pos]) {
+			pos++
+		}
+		if pos >= len(text) {
+			break
+		}
+		switch text[pos] {
+		case '>', '+', '~':
+			combinator = text[pos]
+			pos++
+			for pos < len(text) && isSpace(text[pos]) {
+				pos++
+			}
+			if pos >= len(text) {
+				return selector{}, false
+			}
+		default:
+			if pos == start {
+				return selector{}, false
+			}
+			combinator = ' '
+		}
+	}
+	return sel, len(sel.compounds) > 0
+}
+
+func parseCompound(text string, pos int) (compound, int, bool) {
+	var comp compound
+	start := pos
+	if pos < len(text) && text[pos] == '*' {
+		pos++
+	} else if name, end := readName(text, pos); end > pos {
+		comp.tag = strings.ToLower(name)
+		pos = end
+	}
+
+	for pos < len(text) {
+		switch text[pos] {
+		case '#', '.':
+			name, end := readName(text, pos+1)
+			if end == pos+1 {
+				return compound{}, 0, false
+			}
+			if text[pos] == '#' {
+				comp.id = name
+			} else {
+				comp.classes = append(comp.classes, name)
+			}
+			pos = end
+		case '[':
+			end := indexOutside(text, pos, "]")
+			if end < 0 {
+				return compound{}, 0, false
+			}
+			attr, ok := parseAttrMatcher(text[pos+1 : end])
+			if !ok {
+				return compound{}, 0, false
+			}
+			comp.attrs = append(comp.attrs, attr)
+			pos = end + 1
+		default:
+			// Pseudo-classes and anything else that isn't a combinator are not supported.
+			if text[pos] != '>' && text[pos] != '+' && text[pos] != '~' && !isSpace(text[pos]) {
+				return compound{}, 0, false
+			}
+			return comp, pos, pos > start
+		}
+	}
+	return comp, pos, pos > start
+}
+
+func parseAttrMatcher(text string) (attrMatcher, bool) {
+	i := strings.IndexAny(text, "=~|^$*")
+	if i < 0 {
+		name := strings.TrimSpace(text)
+		return attrMatcher{name: strings.ToLower(name)}, name != ""
+	}
+	attr := attrMatcher{name: strings.ToLower(strings.TrimSpace(text[:i]))}
+	operator, value, ok := strings.Cut(text[i:], "=")
+	if !ok || len(operator) > 1 || attr.name == "" {
+		return attrMatcher{}, false
+	}
+	attr.operator = operator + "="
+
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	} else if strings.ContainsAny(value, " \t\"'") {
+		// Case-sensitivity flags and malformed values are not supported.
+		return attrMatcher{}, false
+	}
+	attr.value = value
+	return attr, true
+}
+
+func readName(text string, pos int) (string, int) {
+	end := pos
+	for end < len(text) {
+		c := text[end]
+		if c == '-' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80 {
+			end++
+			continue
+		}
+		break
+	}
+	return text[pos:end], end
+}
+
+func addSpecificity(s specificity, comp compound) specificity {
+	if comp.id != "" {
+		s[0]++
+	}
+	s[1] += len(comp.classes) + len(comp.attrs)
+	if comp.tag != "" {
+		s[2]++
+	}
+	return s
+}
+
+// matches reports whether the element matches the selector.
+func (sel selector) matches(node *html.Node) bool {
+	return matchFrom(node, sel.compounds)
+}
+
+func matchFrom(node *html.Node, compounds []compound) bool {
+	if !compounds[0].matches(node) {
+		return false
+	}
+	if len(compounds) == 1 {
+		return true
+	}
+	rest := compounds[1:]
+	switch compounds[0].combinator {
+	case '>':
+		parent := parentElement(node)
+		return parent != nil && matchFrom(parent, rest)
+	case '+':
+		prev := previousElement(node)
+		return prev != nil && matchFrom(prev, rest)
+	case '~':
+		for prev := previousElement(node); prev != nil; prev = previousElement(prev) {
+			if matchFrom(prev, rest) {
+				return true
+			}
+		}
+		return false
+	default:
+		for parent := parentElement(node); parent != nil; parent = parentElement(parent) {
+			if matchFrom(parent, rest) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (comp compound) matches(node *html.Node) bool {
+	if node.Type != html.ElementNode {
+		return false
+	}
+	if comp.tag != "" && comp.tag != node.Data {
+		return false
+	}
+	if comp.id != "" && attribute(node, "id") != comp.id {
+		return false
+	}
+	if len(comp.classes) > 0 {
+		classes := strings.Fields(attribute(node, "class"))
+		for _, class := range comp.classes {
+			if !containsString(classes, class) {
+				return false
+			}
+		}
+	}
+	for _, attr := range comp.attrs {
+		if !attr.matches(node) {
+			return false
+		}
+	}
+	return true
+}
+
+func (attr attrMatcher) matches(node *html.Node) bool {
+	var value string
+	found := false
+	for _, a := range node.Attr {
+		if a.Namespace == "" && a.Key == attr.name {
+			value, found = a.Val, true
+			break
+		}
+	}
+	if !found {
+		return false
+	}
+	switch attr.operator {
+	case "":
+		return true
+	case "=":
+		return value == attr.value
+	case "~=":
+		return containsString(strings.Fields(value), attr.value)
+	case "|=":
+		return value == attr.value || strings.HasPrefix(value, attr.value+"-")
+	case "^=":
+		return attr.value != "" && strings.HasPrefix(value, attr.value)
+	case "$=":
+		return attr.value != "" && strings.HasSuffix(value, attr.value)
+	case "*=":
+		return attr.value != "" && strings.Contains(value, attr.value)
+	}
+	return false
+}
+
+func parentElement(node *html.Node) *html.Node {
+	if node.Parent != nil && node.Parent.Type == html.ElementNode {
+		return node.Parent
+	}
+	return nil
+}
+
+func previousElement(node *html.Node) *html.Node {
+	for prev := node.PrevSibling; prev != nil; prev = prev.PrevSibling {
+		if prev.Type == html.ElementNode {
+			return prev
+		}
+	}
+	return nil
+}
+
+func attribute(node *html.Node, key string) string {
+	for _, a := range node.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
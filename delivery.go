@@ -0,0 +1,126 @@
+package sail
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/demianbucik/sail/config"
+	"github.com/demianbucik/sail/mailer"
+	"github.com/demianbucik/sail/outbox"
+)
+
+type deliveryStatus string
+
+const (
+	deliverySent    deliveryStatus = "sent"
+	deliveryQueued  deliveryStatus = "queued"
+	deliveryFailed  deliveryStatus = "failed"
+	deliverySkipped deliveryStatus = "skipped"
+)
+
+// delivery tracks the outcome of the email and the confirmation separately.
+type delivery struct {
+	Email        deliveryStatus `json:"email"`
+	Confirmation deliveryStatus `json:"confirmation"`
+	// Route describes the routing rule that picked the recipient, it's only logged.
+	Route string `json:"-"`
+
+	EmailErr        error `json:"-"`
+	ConfirmationErr error `json:"-"`
+}
+
+// err returns the error that should fail the submission, a failed confirmation only counts when it's required.
+func (result *delivery) err(mode config.ConfirmationMode) error {
+	if result.EmailErr != nil {
+		return result.EmailErr
+	}
+	if mode == config.ConfirmationRequired {
+		return result.ConfirmationErr
+	}
+	return nil
+}
+
+func (service *sailService) sendEmailAndConfirmation(formConf *config.Form, submissionId string, form *EmailForm) *delivery {
+	result := &delivery{Confirmation: deliverySkipped}
+
+	// The routed recipient replaces RECIPIENT_EMAIL and RECIPIENT_NAME in the email, the confirmation and the templates.
+	route := formConf.Route(form.Fields)
+	result.Route = route.String()
+	routed := *formConf
+	routed.RecipientEmail, routed.RecipientName = route.RecipientEmail, route.RecipientName
+	formConf = &routed
+
+	// Quarantined submissions are not assigned, they may never be answered.
+	if assigner, ok := service.assigners[formConf.ID]; ok && !form.Spam.quarantined() {
+		assignee := assigner.next()
+		form.Assignee = &assignee
+	}
+
+	message, err := service.newEmail(formConf, form)
+	if err != nil {
+		result.Email = deliveryFailed
+		result.EmailErr = fmt.Errorf("creating email failed: %w", err)
+		return result
+	}
+	result.Email, result.EmailErr = service.deliver(submissionId+"-email", submissionId, message, "email")
+
+	// Without the notification there is nothing to confirm, and no address to confirm to without an email.
+	// Quarantined submissions aren't confirmed, the address is likely forged.
+	if result.Email == deliveryFailed || !formConf.ConfirmationEnabled() || form.Email == "" || form.Spam.quarantined() {
+		return result
+	}
+
+	confirmation, err := service.newConfirmation(formConf, form)
+	if err != nil {
+		result.Confirmation = deliveryFailed
+		result.ConfirmationErr = fmt.Errorf("creating confirmation failed: %w", err)
+		return result
+	}
+	result.Confirmation, result.ConfirmationErr = service.deliver(
+		submissionId+"-confirmation", submissionId, confirmation, "confirmation",
+	)
+
+	return result
+}
+
+// deliver queues the message when the outbox is enabled, otherwise it's sent right away.
+// Messages that fail to send are stored as dead letters.
+func (service *sailService) deliver(id, submissionId string, message *mailer.Message, kind string) (deliveryStatus, error) {
+	if service.outbox != nil {
+		if err := service.outbox.Enqueue(id, submissionId, message); err != nil {
+			return deliveryFailed, fmt.Errorf("queueing %s failed: %w", kind, err)
+		}
+		return deliveryQueued, nil
+	}
+
+	if err := service.sendEmail(message); err != nil {
+		err = fmt.Errorf("sending %s failed: %w", kind, err)
+		return deliveryFailed, service.storeDeadLetter(id, submissionId, message, err)
+	}
+	return deliverySent, nil
+}
+
+func (service *sailService) sendEmail(message *mailer.Message) error {
+	// Retries and provider failover are handled by the mailer.
+	return service.emailClient.Send(message)
+}
+
+// storeDeadLetter keeps the undelivered message for a later replay and returns the original error.
+func (service *sailService) storeDeadLetter(id, submissionId string, message *mailer.Message, sendErr error) error {
+	if service.deadLetters == nil {
+		return sendErr
+	}
+	now := time.Now()
+	err := service.deadLetters.Put(&outbox.Entry{
+		ID:           id,
+		SubmissionID: submissionId,
+		Message:      message,
+		CreatedAt:    now,
+		Attempts:     1,
+		Errors:       []outbox.AttemptError{{Time: now, Error: sendErr.Error()}},
+	})
+	if err != nil {
+		return fmt.Errorf("%w, storing dead letter failed: %v", sendErr, err)
+	}
+	return sendErr
+}
@@ -0,0 +1,51 @@
+package sail
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	routes := `
+CONFIRMATION_MODE: "off"
+FORM_FIELDS:
+  - {name: name}
+  - {name: email, type: email, required: true}
+  - {name: department}
+  - {name: subject}
+  - {name: message}
+ROUTES:
+  - {field: department, equals: sales, recipientEmail: sales@mydomain.com, recipientName: Sales}
+  - {field: subject, contains: Invoice, recipientEmail: billing@mydomain.com}
+  - {field: message, regex: "(?i)refund|chargeback", recipientEmail: refunds@mydomain.com}
+`
+	tests := []struct {
+		name     string
+		settings string
+		values   url.Values
+		want     string
+	}{
+		{"equals", routes, url.Values{"department": {"sales"}}, `"Sales" <sales@mydomain.com>`},
+		{"equals is exact", routes, url.Values{"department": {"Sales"}}, `"Bob Stone" <bob.stone@gmail.com>`},
+		{"contains ignores the case", routes, url.Values{"subject": {"My invoice"}}, "<billing@mydomain.com>"},
+		{"regex", routes, url.Values{"message": {"I want a REFUND"}}, "<refunds@mydomain.com>"},
+		{"first matching rule", routes, url.Values{"department": {"sales"}, "subject": {"invoice"}}, `"Sales" <sales@mydomain.com>`},
+		{"no rule matches", routes, url.Values{"message": {"Hello"}}, `"Bob Stone" <bob.stone@gmail.com>`},
+		{"default rule", routes + "  - {default: true, recipientEmail: hello@mydomain.com}\n",
+			url.Values{"message": {"Hello"}}, "<hello@mydomain.com>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, sent := newTestService(t, tt.settings)
+			tt.values.Set("email", "bob@example.com")
+
+			if resp := submit(service, tt.values.Encode()); resp.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", resp.Code, resp.Body)
+			}
+			if to := sent.last().To; len(to) != 1 || to[0].String() != tt.want {
+				t.Errorf("email to %v, want %s", to, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package sail
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/demianbucik/sail/config"
+)
+
+const dateLayout = "2006-01-02"
+
+// validateField normalises the submitted values of a declared field and returns the reason when they're invalid.
+func validateField(field *config.FormField, values []string) (string, string) {
+	var value string
+	if len(values) > 0 {
+		value = strings.TrimSpace(values[0])
+	}
+
+	if field.Type == config.FieldCheckbox {
+		checked := isChecked(value)
+		if field.Required && !checked {
+			return "false", "required"
+		}
+		return strconv.FormatBool(checked), ""
+	}
+
+	if value == "" {
+		if field.Required {
+			return "", "required"
+		}
+		return "", ""
+	}
+
+	length := utf8.RuneCountInString(value)
+	if field.MinLength > 0 && length < field.MinLength {
+		return value, fmt.Sprintf("must be at least %d characters long", field.MinLength)
+	}
+	if field.MaxLength > 0 && length > field.MaxLength {
+		return value, fmt.Sprintf("must be at most %d characters long", field.MaxLength)
+	}
+
+	switch field.Type {
+	case config.FieldEmail:
+		if !isValidEmail(value) {
+			return value, "invalid email address"
+		}
+	case config.FieldNumber:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return value, "invalid number"
+		}
+		if field.Min != nil && number < *field.Min {
+			return value, fmt.Sprintf("must be at least %v", *field.Min)
+		}
+		if field.Max != nil && number > *field.Max {
+			return value, fmt.Sprintf("must be at most %v", *field.Max)
+		}
+	case config.FieldSelect:
+		if !containsString(field.Options, value) {
+			return value, "invalid option"
+		}
+	case config.FieldDate:
+		if _, err := time.Parse(dateLayout, value); err != nil {
+			return value, "invalid date, expected YYYY-MM-DD"
+		}
+	}
+
+	if !field.MatchesPattern(value) {
+		return value, "invalid format"
+	}
+	return value, ""
+}
+
+// isChecked interprets HTML checkboxes, which send "on" or a custom value only when checked, and JSON booleans.
+func isChecked(value string) bool {
+	switch strings.ToLower(value) {
+	case "", "false", "off", "0", "no":
+		return false
+	default:
+		return true
+	}
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
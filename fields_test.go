@@ -0,0 +1,95 @@
+package sail
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+const fieldSettings = `
+FORM_FIELDS:
+  - {name: name, required: true, minLength: 2, maxLength: 10}
+  - {name: email, type: email, required: true}
+  - {name: phone, pattern: "\\+?[0-9 ]+"}
+  - {name: topic, type: select, options: [sales, support]}
+  - {name: terms, type: checkbox, required: true}
+  - {name: newsletter, type: checkbox}
+  - {name: date, type: date}
+  - {name: guests, type: number, min: 1, max: 10}
+`
+
+func TestParseForm(t *testing.T) {
+	valid := url.Values{
+		"name":  {" Bob "},
+		"email": {"bob@example.com"},
+		"terms": {"on"},
+	}
+	// with returns the valid values with the pairs of keys and values changed.
+	with := func(pairs ...string) url.Values {
+		values := url.Values{}
+		for k, v := range valid {
+			values[k] = v
+		}
+		for i := 0; i+1 < len(pairs); i += 2 {
+			values.Set(pairs[i], pairs[i+1])
+		}
+		return values
+	}
+
+	tests := []struct {
+		name       string
+		values     url.Values
+		wantFields map[string]string
+		wantErrors ValidationError
+	}{
+		{"valid", valid, map[string]string{
+			"name": "Bob", "email": "bob@example.com", "phone": "", "topic": "", "terms": "true",
+			"newsletter": "false", "date": "", "guests": "",
+		}, nil},
+		{"all fields", with("phone", "+386 40 123", "topic", "sales", "newsletter", "yes", "date", "2024-02-29", "guests", "10"),
+			map[string]string{"phone": "+386 40 123", "topic": "sales", "newsletter": "true", "date": "2024-02-29", "guests": "10"}, nil},
+		{"required", url.Values{"name": {"  "}}, nil, ValidationError{"name": "required", "email": "required", "terms": "required"}},
+		{"min length", with("name", "B"), nil, ValidationError{"name": "must be at least 2 characters long"}},
+		{"max length in characters", with("name", "Bobčekčekče"), nil, ValidationError{"name": "must be at most 10 characters long"}},
+		{"max length", with("name", "Žžžžžžžžžž"), map[string]string{"name": "Žžžžžžžžžž"}, nil},
+		{"email", with("email", "Bob <bob@example.com>"), nil, ValidationError{"email": "invalid email address"}},
+		{"pattern", with("phone", "call me"), nil, ValidationError{"phone": "invalid format"}},
+		{"pattern matches the whole value", with("phone", "123 and more"), nil, ValidationError{"phone": "invalid format"}},
+		{"select", with("topic", "Sales"), nil, ValidationError{"topic": "invalid option"}},
+		{"unchecked checkbox", with("terms", "false"), nil, ValidationError{"terms": "required"}},
+		{"checkbox value", with("newsletter", "1"), map[string]string{"newsletter": "true"}, nil},
+		{"date", with("date", "2023-02-29"), nil, ValidationError{"date": "invalid date, expected YYYY-MM-DD"}},
+		{"number", with("guests", "two"), nil, ValidationError{"guests": "invalid number"}},
+		{"number below min", with("guests", "0.5"), nil, ValidationError{"guests": "must be at least 1"}},
+		{"number above max", with("guests", "11"), nil, ValidationError{"guests": "must be at most 10"}},
+	}
+	service, _ := newTestService(t, fieldSettings)
+	formConf, _ := service.env.Form("")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/send-email", nil)
+			form, err := service.parseForm(formConf, request, tt.values)
+			if tt.wantErrors != nil {
+				var validationErr ValidationError
+				if !errors.As(err, &validationErr) || !reflect.DeepEqual(validationErr, tt.wantErrors) {
+					t.Errorf("parseForm() = %v, want %v", err, tt.wantErrors)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseForm() = %v", err)
+			}
+			if form.Name != form.Fields["name"] || form.Email != form.Fields["email"] {
+				t.Errorf("well-known fields %q, %q don't match %v", form.Name, form.Email, form.Fields)
+			}
+			for name, want := range tt.wantFields {
+				if form.Fields[name] != want {
+					t.Errorf("field %s = %q, want %q", name, form.Fields[name], want)
+				}
+			}
+		})
+	}
+}
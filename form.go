@@ -1,33 +1,201 @@
 package sail
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
 	"net/http"
+	"net/mail"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demianbucik/sail/config"
+	"github.com/demianbucik/sail/mailer"
+)
+
+// maxFormSize limits the size of form fields, not counting attachments.
+const maxFormSize = 10 << 20
+
+const (
+	reCaptchaResponseField = "g-recaptcha-response"
+	// formIdField selects the form when the ID isn't part of the path.
+	formIdField = "form-id"
 )
 
 type EmailForm struct {
-	Name    string `schema:"name,required" json:"name"`
-	Email   string `schema:"email,required" json:"email"`
-	Subject string `schema:"subject,required" json:"subject"`
-	Message string `schema:"message,required" json:"message"`
+	// Values of the well-known fields, empty when they are not declared.
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+
+	// Fields contains the values of all declared fields, including the well-known ones.
+	Fields map[string]string `json:"fields"`
+	// AllFields contains the first value of every submitted field, including the undeclared ones.
+	AllFields map[string]string `json:"-"`
+
+	ReCaptchaResponse string `json:"g-recaptcha-response"`
+
+	HoneypotValue string `json:"honeypot-value"`
+
+	TimeTrapToken string `json:"form-token"`
+
+	Attachments []mailer.Attachment `json:"-"`
 
-	ReCaptchaResponse string `schema:"g-recaptcha-response" json:"g-recaptcha-response"`
+	// Languages preferred by the visitor, most preferred first.
+	Languages []string `json:"languages,omitempty"`
 
-	HoneypotValue string `schema:"-" json:"honeypot-value"`
+	// Assignee is picked when the form has assignees, just before the email is sent.
+	Assignee *mailer.Address `json:"assignee,omitempty"`
+
+	// Spam is the spam score of the submission, nil when the form has no spam rules.
+	Spam *spamCheck `json:"spam,omitempty"`
+
+	// Request metadata, it's already part of the request log.
+	SubmissionId string    `json:"-"`
+	SubmittedAt  time.Time `json:"-"`
+	ClientIp     string    `json:"-"`
+	UserAgent    string    `json:"-"`
+	Referer      string    `json:"-"`
 }
 
-func (service *sailService) parseForm(request *http.Request) (*EmailForm, error) {
-	if err := request.ParseForm(); err != nil {
-		return nil, err
+// ValidationError maps invalid form fields to the reason they were rejected.
+type ValidationError map[string]string
+
+func (err ValidationError) Error() string {
+	fields := make([]string, 0, len(err))
+	for field := range err {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	reasons := make([]string, len(fields))
+	for i, field := range fields {
+		reasons[i] = fmt.Sprintf("%s: %s", field, err[field])
+	}
+	return "invalid form: " + strings.Join(reasons, ", ")
+}
+
+func (service *sailService) parseForm(formConf *config.Form, request *http.Request, values url.Values) (*EmailForm, error) {
+	form := &EmailForm{
+		Fields:            make(map[string]string),
+		AllFields:         make(map[string]string),
+		ReCaptchaResponse: values.Get(reCaptchaResponseField),
+		TimeTrapToken:     values.Get(timeTrapField),
 	}
+	for name := range values {
+		if name == reCaptchaResponseField || name == formIdField || name == timeTrapField ||
+			formConf.HoneypotCheckEnabled() && name == *formConf.HoneypotField {
+			continue
+		}
+		form.AllFields[name] = values.Get(name)
+	}
+	validationErr := ValidationError{}
+
+	for _, field := range formConf.Fields() {
+		value, reason := validateField(&field, values[field.Name])
+		if reason != "" {
+			validationErr[field.Name] = reason
+		}
+		form.Fields[field.Name] = value
+	}
+	form.Name = form.Fields["name"]
+	form.Email = form.Fields["email"]
+	form.Subject = form.Fields["subject"]
+	form.Message = form.Fields["message"]
 
-	form := &EmailForm{}
-	if err := service.formDecoder.Decode(form, request.Form); err != nil {
+	form.Languages = preferredLanguages(values.Get(langField), request.Header.Get("Accept-Language"))
+
+	attachments, err := service.readAttachments(request)
+	var attachmentsErr ValidationError
+	if errors.As(err, &attachmentsErr) {
+		for field, reason := range attachmentsErr {
+			validationErr[field] = reason
+		}
+	} else if err != nil {
 		return nil, err
 	}
+	if len(validationErr) > 0 {
+		return nil, validationErr
+	}
+	form.Attachments = attachments
 
-	if service.env.HoneypotCheckEnabled() {
-		form.HoneypotValue = request.Form.Get(service.env.HoneypotField)
+	if formConf.HoneypotCheckEnabled() {
+		form.HoneypotValue = values.Get(*formConf.HoneypotField)
 	}
 
 	return form, nil
 }
+
+// readFormValues reads urlencoded, multipart and JSON request bodies, as well as the URL query.
+func (service *sailService) readFormValues(request *http.Request) (url.Values, error) {
+	mediaType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		return readJSONValues(request)
+	case "multipart/form-data":
+		maxSize := int64(maxFormSize)
+		if service.env.AttachmentsEnabled() {
+			maxSize += service.env.MaxTotalSize()
+		}
+		request.Body = http.MaxBytesReader(nil, request.Body, maxSize)
+		if err := request.ParseMultipartForm(maxSize); err != nil {
+			return nil, err
+		}
+		return request.Form, nil
+	default:
+		if err := request.ParseForm(); err != nil {
+			return nil, err
+		}
+		return request.Form, nil
+	}
+}
+
+func readJSONValues(request *http.Request) (url.Values, error) {
+	body := map[string]any{}
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, request.Body, maxFormSize))
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid JSON body: %w", err)
+	}
+
+	values := request.URL.Query()
+	for key, value := range body {
+		switch v := value.(type) {
+		case []any:
+			for _, item := range v {
+				values.Add(key, jsonValueToString(item))
+			}
+		default:
+			values.Set(key, jsonValueToString(v))
+		}
+	}
+	// Keep the values available for logging, like with urlencoded forms.
+	request.Form = values
+	return values, nil
+}
+
+func jsonValueToString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
+
+func isValidEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
@@ -0,0 +1,43 @@
+package sail
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadJSONValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		body    string
+		want    url.Values
+		wantErr bool
+	}{
+		{"strings", "/", `{"name": "Bob", "message": "Hi\nthere"}`, url.Values{"name": {"Bob"}, "message": {"Hi\nthere"}}, false},
+		{"numbers keep their format", "/", `{"guests": 10, "price": 1e3, "id": 12345678901234567890}`,
+			url.Values{"guests": {"10"}, "price": {"1e3"}, "id": {"12345678901234567890"}}, false},
+		{"booleans and null", "/", `{"terms": true, "newsletter": false, "phone": null}`,
+			url.Values{"terms": {"true"}, "newsletter": {"false"}, "phone": {""}}, false},
+		{"arrays", "/", `{"topics": ["sales", 2, true]}`, url.Values{"topics": {"sales", "2", "true"}}, false},
+		{"objects", "/", `{"address": {"city": "Ljubljana"}}`, url.Values{"address": {`{"city":"Ljubljana"}`}}, false},
+		{"query", "/?form-id=sales&name=Alice", `{"name": "Bob"}`, url.Values{"form-id": {"sales"}, "name": {"Bob"}}, false},
+		{"invalid", "/", `{"name": }`, nil, true},
+		{"not an object", "/", `"Bob"`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			values, err := readJSONValues(request)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readJSONValues() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(values, tt.want) {
+				t.Errorf("readJSONValues() = %v, want %v", values, tt.want)
+			}
+		})
+	}
+}
@@ -1,22 +1,25 @@
 package sail
 
 import (
-	"bytes"
+	"context"
+	"crypto/rand"
 	"embed"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io/fs"
+	"math"
 	"net/http"
 	"strings"
 	"sync"
-	"text/template"
 	"time"
 
 	"github.com/apex/log"
-	"github.com/gorilla/schema"
-	"github.com/sendgrid/sendgrid-go"
-	"github.com/sendgrid/sendgrid-go/helpers/mail"
-
 	"github.com/demianbucik/sail/config"
+	"github.com/demianbucik/sail/mailer"
+	"github.com/demianbucik/sail/outbox"
+	"github.com/demianbucik/sail/ratelimit"
+	"github.com/demianbucik/sail/spam"
 	"github.com/demianbucik/sail/utils"
 )
 
@@ -32,6 +35,8 @@ var templatesFS embed.FS
 var (
 	once    sync.Once
 	service *sailService
+	// serviceMu guards service for the readers that don't go through Init, such as TimeTrapToken.
+	serviceMu sync.RWMutex
 )
 
 var SendEmailHandler = utils.MiddlewareWrap(
@@ -57,7 +62,13 @@ func Init(parseFunc func(*config.Environ) error) {
 			return
 		}
 
-		service, initErr = newSailService(env)
+		var s *sailService
+		if s, initErr = newSailService(env); initErr != nil {
+			return
+		}
+		serviceMu.Lock()
+		service = s
+		serviceMu.Unlock()
 	})
 	if initErr != nil {
 		once = sync.Once{}
@@ -68,118 +79,277 @@ func Init(parseFunc func(*config.Environ) error) {
 type sailService struct {
 	env *config.Environ
 
-	emailClient     SendGridClient
-	reCaptchaClient ReCaptchaClient
-
-	formDecoder *schema.Decoder
-	templates   *template.Template
+	emailClient mailer.Mailer
+	// Forms may use different reCAPTCHA keys, clients are mapped by form ID.
+	reCaptchaClients map[string]ReCaptchaClient
+	// Assigners of the forms with assignees, mapped by form ID.
+	assigners map[string]*assigner
+	// Buckets of the submission rate limits, see RateLimitStore.
+	rateLimits ratelimit.Store
+	// Hashes of the submitted messages, see SpamHashStore.
+	spamHashes spam.HashStore
+	// Optional, emails are sent synchronously when nil
+	outbox *outbox.Worker
+	// Optional, undelivered emails are only logged when nil
+	deadLetters outbox.Store
+
+	templates *templateLoader
 }
 
 func newSailService(env *config.Environ) (*sailService, error) {
-	emailClient := sendgrid.NewSendClient(env.SendGridApiKey)
-
-	reCaptchaClient := &utils.ReCaptcha{
-		Client:  http.Client{Timeout: reCaptchaTimeout},
-		Secret:  env.ReCaptchaSecretKey,
-		Version: env.ReCaptchaVersion,
+	emailClient, err := NewMailer(env)
+	if err != nil {
+		return nil, err
 	}
 
-	formDecoder := schema.NewDecoder()
-	formDecoder.IgnoreUnknownKeys(true)
+	reCaptchaClients := make(map[string]ReCaptchaClient)
+	assigners := make(map[string]*assigner)
+	for _, form := range env.AllForms() {
+		if err = validateHeaders(form); err != nil {
+			if form.ID != "" {
+				return nil, fmt.Errorf("FORMS form '%s': %w", form.ID, err)
+			}
+			return nil, err
+		}
+		reCaptchaClients[form.ID] = &utils.ReCaptcha{
+			Client:  http.Client{Timeout: reCaptchaTimeout},
+			Secret:  form.ReCaptchaSecretKey,
+			Version: form.ReCaptchaVersion,
+		}
+		if form.AssignmentEnabled() {
+			assigners[form.ID] = newAssigner(form.Assignees, form.Assignment())
+		}
+	}
 
-	templates, err := template.ParseFS(templatesFS, "templates/*")
+	embedded, err := fs.Sub(templatesFS, "templates")
+	if err != nil {
+		return nil, err
+	}
+	templates, err := newTemplateLoader(embedded, env.TemplatesDir, bool(env.TemplatesReload), bool(env.InlineCSS))
 	if err != nil {
 		return nil, err
 	}
 
-	return &sailService{
-		env:             env,
-		emailClient:     emailClient,
-		reCaptchaClient: reCaptchaClient,
-		formDecoder:     formDecoder,
-		templates:       templates.Option("missingkey=error"),
-	}, nil
+	rateLimits := RateLimitStore
+	if rateLimits == nil {
+		rateLimits = ratelimit.NewMemoryStore()
+	}
+	spamHashes := SpamHashStore
+	if spamHashes == nil {
+		spamHashes = spam.NewMemoryStore()
+	}
+
+	service := &sailService{
+		env:              env,
+		emailClient:      emailClient,
+		reCaptchaClients: reCaptchaClients,
+		assigners:        assigners,
+		rateLimits:       rateLimits,
+		spamHashes:       spamHashes,
+		templates:        templates,
+	}
+
+	if env.DeadLettersEnabled() {
+		service.deadLetters, err = outbox.NewFileStore(env.DeadLetterDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if env.OutboxEnabled() {
+		store, err := outbox.NewFileStore(env.OutboxDir)
+		if err != nil {
+			return nil, err
+		}
+		service.outbox = outbox.NewWorker(store, emailClient)
+		if service.deadLetters != nil {
+			service.outbox.DeadLetters = service.deadLetters
+			service.outbox.MaxAttempts = env.MaxAttempts()
+		}
+		if env.OutboxRetryInterval != 0 {
+			service.outbox.BackOff = time.Duration(env.OutboxRetryInterval)
+		}
+		if env.OutboxMaxRetryInterval != 0 {
+			service.outbox.MaxBackOff = time.Duration(env.OutboxMaxRetryInterval)
+		}
+		go service.outbox.Run(context.Background())
+	}
+
+	return service, nil
 }
 
 func (service *sailService) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if isTimeTrapRequest(request) {
+		service.serveTimeTrapToken(writer, request)
+		return
+	}
+
 	reqCtx := request.Context().Value(utils.RequestCtxKey).(*utils.RequestContext)
 
-	form, err := service.parseForm(request)
+	submissionId := newSubmissionId()
+	reqCtx.LogEntry = reqCtx.LogEntry.WithField("submissionId", submissionId)
+
+	values, err := service.readFormValues(request)
+	if request.MultipartForm != nil {
+		defer request.MultipartForm.RemoveAll()
+	}
+	if err != nil {
+		reqCtx.RequestLog.Finalize()
+		reqCtx.LogEntry.WithError(err).Info("Email rejected - invalid request body")
+
+		// The form ID may still be known from the path, so its error page can be used.
+		formConf, _ := service.env.Form(formIdFromPath(request.URL.Path))
+		service.respond(writer, request, formConf, &submissionResponse{Code: http.StatusBadRequest, SubmissionId: submissionId})
+		return
+	}
+
+	formId := formIdFromPath(request.URL.Path)
+	if formId == "" {
+		formId = values.Get(formIdField)
+	}
+	reqCtx.LogEntry = reqCtx.LogEntry.WithField("formId", formId)
+
+	formConf, ok := service.env.Form(formId)
+	if !ok {
+		reqCtx.RequestLog.Finalize()
+		reqCtx.LogEntry.WithField("httpForm", request.Form).Info("Email rejected - unknown form")
+
+		service.respond(writer, request, nil, &submissionResponse{
+			Code:         http.StatusNotFound,
+			SubmissionId: submissionId,
+			Message:      "Unknown form",
+		})
+		return
+	}
+
+	form, err := service.parseForm(formConf, request, values)
 	if err != nil {
 		reqCtx.RequestLog.Finalize()
 		reqCtx.LogEntry.WithError(err).WithField("httpForm", request.Form).Info("Email rejected - invalid form")
 
-		http.Redirect(writer, request, service.env.ErrorPage, http.StatusSeeOther)
+		resp := &submissionResponse{Code: http.StatusBadRequest, SubmissionId: submissionId}
+		var validationErr ValidationError
+		if errors.As(err, &validationErr) {
+			resp.Code = http.StatusUnprocessableEntity
+			resp.Errors = validationErr
+		}
+		service.respond(writer, request, formConf, resp)
 		return
 	}
 
 	reqCtx.LogEntry = reqCtx.LogEntry.WithField("emailForm", form)
 
-	clientIp := reqCtx.RequestLog.RemoteIp
-	if err = service.verify(form, clientIp); err != nil {
+	form.SubmissionId = submissionId
+	form.SubmittedAt = reqCtx.RequestLog.Timestamp
+	form.ClientIp = reqCtx.RequestLog.RemoteIp
+	form.UserAgent = request.UserAgent()
+	form.Referer = request.Referer()
+
+	// The IP limit uses the address added by the trusted proxies, the header can be forged by the client.
+	if err = service.checkRateLimits(formConf, form, utils.ClientIp(request, int(service.env.TrustedProxies))); err != nil {
+		reqCtx.RequestLog.Finalize()
+		reqCtx.LogEntry.WithError(err).Info("Email rejected - rate limit")
+
+		resp := &submissionResponse{
+			Code:         http.StatusTooManyRequests,
+			SubmissionId: submissionId,
+			Message:      "Too many submissions",
+		}
+		var limitErr *rateLimitError
+		if errors.As(err, &limitErr) {
+			resp.RetryAfter = int(math.Ceil(limitErr.retryAfter.Seconds()))
+		}
+		service.respond(writer, request, formConf, resp)
+		return
+	}
+
+	if err = service.verify(formConf, form, form.ClientIp); err != nil {
 		reqCtx.RequestLog.Finalize()
 		reqCtx.LogEntry.WithError(err).Info("Email rejected - verification")
 
-		http.Redirect(writer, request, service.env.ErrorPage, http.StatusSeeOther)
+		service.respond(writer, request, formConf, &submissionResponse{
+			Code:         http.StatusForbidden,
+			SubmissionId: submissionId,
+			Message:      "Verification failed",
+		})
 		return
 	}
 
-	if err = service.sendEmailAndConfirmation(form); err != nil {
+	// Spammers get the same response as for a failed verification, so they don't learn about the rules.
+	form.Spam = service.checkSpam(formConf, form)
+	if form.Spam.rejected() {
+		reqCtx.RequestLog.Finalize()
+		reqCtx.LogEntry.Info("Email rejected - spam")
+
+		service.respond(writer, request, formConf, &submissionResponse{
+			Code:         http.StatusForbidden,
+			SubmissionId: submissionId,
+			Message:      "Verification failed",
+		})
+		return
+	}
+
+	result := service.sendEmailAndConfirmation(formConf, submissionId, form)
+	reqCtx.LogEntry = reqCtx.LogEntry.WithField("delivery", result).WithField("route", result.Route)
+
+	if err = result.err(formConf.Confirmation()); err != nil {
+		service.releaseTimeTrapToken(formConf, form)
+
 		reqCtx.RequestLog.Finalize()
 		reqCtx.LogEntry.WithError(err).Warn("Sending email failed")
 
-		http.Redirect(writer, request, service.env.ErrorPage, http.StatusSeeOther)
+		service.respond(writer, request, formConf, &submissionResponse{
+			Code:         http.StatusBadGateway,
+			SubmissionId: submissionId,
+			Message:      "Sending email failed",
+			Delivery:     result,
+		})
 		return
 	}
 
+	service.recordSpamHashes(form)
+
 	reqCtx.RequestLog.Finalize()
-	reqCtx.LogEntry.Info("Email sent successfully")
+	if result.ConfirmationErr != nil {
+		reqCtx.LogEntry.WithError(result.ConfirmationErr).Warn("Sending confirmation failed")
+	}
 
-	http.Redirect(writer, request, service.env.SuccessPage, http.StatusSeeOther)
+	resp := &submissionResponse{Code: http.StatusOK, SubmissionId: submissionId, Delivery: result}
+	if result.Email == deliveryQueued {
+		reqCtx.LogEntry.Info("Email queued successfully")
+		resp.Code = http.StatusAccepted
+	} else {
+		reqCtx.LogEntry.Info("Email sent successfully")
+	}
+
+	service.respond(writer, request, formConf, resp)
 }
 
-func (service *sailService) verify(form *EmailForm, clientIp string) error {
-	if err := service.verifyReCaptcha(form.ReCaptchaResponse, clientIp); err != nil {
+func (service *sailService) verify(formConf *config.Form, form *EmailForm, clientIp string) error {
+	if err := service.verifyReCaptcha(formConf, form.ReCaptchaResponse, clientIp); err != nil {
 		return fmt.Errorf("recaptcha verification failed: %w", err)
 	}
-	if err := service.checkHoneypot(form); err != nil {
+	if err := service.checkHoneypot(formConf, form); err != nil {
 		return fmt.Errorf("honeypot check failed: %w", err)
 	}
-	return nil
-}
-
-func (service *sailService) sendEmailAndConfirmation(form *EmailForm) error {
-	message, err := service.newEmail(form)
-	if err != nil {
-		return fmt.Errorf("creating email failed: %w", err)
-	}
-	if err = service.sendEmail(message); err != nil {
-		return fmt.Errorf("sending email failed: %w", err)
-	}
-
-	confirmation, err := service.newConfirmation(form)
-	if err != nil {
-		return fmt.Errorf("creating confirmation failed: %w", err)
-	}
-	if err = service.sendEmail(confirmation); err != nil {
-		return fmt.Errorf("sending confirmation failed: %w", err)
+	if err := service.checkTimeTrap(formConf, form); err != nil {
+		return fmt.Errorf("time trap check failed: %w", err)
 	}
-
 	return nil
 }
 
-func (service *sailService) checkHoneypot(form *EmailForm) error {
-	if !service.env.HoneypotCheckEnabled() {
+func (service *sailService) checkHoneypot(formConf *config.Form, form *EmailForm) error {
+	if !formConf.HoneypotCheckEnabled() {
 		return nil
 	}
 	if form.HoneypotValue != "" {
-		return fmt.Errorf("invalid '%s' value '%s'", service.env.HoneypotField, form.HoneypotValue)
+		return fmt.Errorf("invalid '%s' value '%s'", *formConf.HoneypotField, form.HoneypotValue)
 	}
 	return nil
 }
 
-func (service *sailService) verifyReCaptcha(response, clientIp string) error {
-	if !service.env.ReCaptchaEnabled() {
+func (service *sailService) verifyReCaptcha(formConf *config.Form, response, clientIp string) error {
+	if !formConf.ReCaptchaEnabled() {
 		return nil
 	}
 	if response == "" {
@@ -187,9 +357,9 @@ func (service *sailService) verifyReCaptcha(response, clientIp string) error {
 	}
 	var err error
 	utils.Retry(retries, retryBackOff, func() error {
-		err = service.reCaptchaClient.Verify(response, utils.VerifyOptions{
+		err = service.reCaptchaClients[formConf.ID].Verify(response, utils.VerifyOptions{
 			RemoteIp:       clientIp,
-			ScoreThreshold: float64(service.env.ReCaptchaV3Threshold),
+			ScoreThreshold: float64(formConf.ReCaptchaV3Threshold),
 		})
 		if v, ok := err.(utils.VerifyError); ok && v.IsHttpError {
 			return err
@@ -199,79 +369,201 @@ func (service *sailService) verifyReCaptcha(response, clientIp string) error {
 	return err
 }
 
-func (service *sailService) sendEmail(message *mail.SGMailV3) error {
-	return utils.Retry(retries, retryBackOff, func() error {
-		resp, err := service.emailClient.Send(message)
-		if err != nil {
-			return err
-		}
-		if resp.StatusCode >= 400 {
-			return fmt.Errorf("response code '%d' not ok, body '%s'", resp.StatusCode, resp.Body)
-		}
-		return nil
-	})
-}
+func (service *sailService) newEmail(formConf *config.Form, form *EmailForm) (*mailer.Message, error) {
+	data := templateData(formConf, form)
+	subject, err := renderHeader(formConf.EmailSubject, formSubject(form), data)
+	if err != nil {
+		return nil, fmt.Errorf("rendering EMAIL_SUBJECT failed: %w", err)
+	}
+	if form.Spam.quarantined() {
+		subject = quarantineSubjectPrefix + subject
+	}
+	fromName, err := renderHeader(formConf.EmailFromName, formConf.NoReplyName, data)
+	if err != nil {
+		return nil, fmt.Errorf("rendering EMAIL_FROM_NAME failed: %w", err)
+	}
 
-func (service *sailService) newEmail(form *EmailForm) (*mail.SGMailV3, error) {
-	from := mail.NewEmail(service.env.NoReplyName, service.env.NoReplyEmail)
-	to := mail.NewEmail(service.env.RecipientName, service.env.RecipientEmail)
-	replyTo := mail.NewEmail(form.Name, form.Email)
+	email := &mailer.Message{
+		From:    mailer.NewAddress(fromName, formConf.NoReplyEmail),
+		To:      []mailer.Address{mailer.NewAddress(formConf.RecipientName, formConf.RecipientEmail)},
+		Cc:      formConf.RecipientCc,
+		Bcc:     formConf.RecipientBcc,
+		Subject: subject,
+		// Uploaded files are only forwarded to the recipients.
+		Attachments: form.Attachments,
+	}
+	email.To = append(email.To, formConf.RecipientTo...)
+	if form.Assignee != nil {
+		email.To = append(email.To, *form.Assignee)
+	}
+	if form.Spam.quarantined() && formConf.SpamQuarantineEmail.Email != "" {
+		email.To = []mailer.Address{mailer.Address(formConf.SpamQuarantineEmail)}
+		email.Cc, email.Bcc = nil, nil
+	}
+	if form.Email != "" {
+		replyTo := mailer.NewAddress(form.Name, form.Email)
+		email.ReplyTo = &replyTo
+	}
 
-	body, err := service.createBodyFromTemplate(service.env.EmailTemplateFile, form)
+	// The local template is rendered even with a dynamic template, other providers in the chain may need it.
+	email.Text, email.HTML, err = service.createBodyFromTemplate(formConf.EmailTemplateFile, data)
 	if err != nil {
 		return nil, err
 	}
-
-	contentType := getContentType(body)
-
-	email := mail.NewSingleEmail(from, form.Subject, to, "", "")
-	email.AddContent(mail.NewContent(contentType, string(body)))
-	email.SetReplyTo(replyTo)
+	email.SendGrid = sendGridOptions(formConf, formConf.SendGridEmailTemplateId, 0, subject, data)
 
 	return email, nil
 }
 
-func (service *sailService) newConfirmation(form *EmailForm) (*mail.SGMailV3, error) {
-	from := mail.NewEmail(service.env.NoReplyName, service.env.NoReplyEmail)
-	to := mail.NewEmail(form.Name, form.Email)
-	replyTo := mail.NewEmail(service.env.RecipientName, service.env.RecipientEmail)
+func (service *sailService) newConfirmation(formConf *config.Form, form *EmailForm) (*mailer.Message, error) {
+	// Replies go straight to the assignee, when the submission has one.
+	replyTo := mailer.NewAddress(formConf.RecipientName, formConf.RecipientEmail)
+	if form.Assignee != nil {
+		replyTo = *form.Assignee
+	}
 
-	body, err := service.createBodyFromTemplate(service.env.ConfirmationTemplateFile, form)
+	templates, err := service.templates.load()
 	if err != nil {
 		return nil, err
 	}
+	// Confirmations are sent in the visitor's language, when there is a localized template or subject.
+	templateName, subjectTemplate := formConf.ConfirmationTemplateFile, formConf.ConfirmationSubject
+	if lang := confirmationLanguage(templates, formConf, form.Languages); lang != "" {
+		if templates.has(localizedName(templateName, lang)) {
+			templateName = localizedName(templateName, lang)
+		}
+		if localized, ok := formConf.ConfirmationSubjects[lang]; ok {
+			subjectTemplate = localized
+		}
+	}
 
-	contentType := getContentType(body)
+	data := templateData(formConf, form)
+	subject, err := renderHeader(subjectTemplate, formSubject(form), data)
+	if err != nil {
+		return nil, fmt.Errorf("rendering CONFIRMATION_SUBJECT failed: %w", err)
+	}
+	fromName, err := renderHeader(formConf.ConfirmationFromName, formConf.NoReplyName, data)
+	if err != nil {
+		return nil, fmt.Errorf("rendering CONFIRMATION_FROM_NAME failed: %w", err)
+	}
 
-	email := mail.NewSingleEmail(from, form.Subject, to, "", "")
-	email.AddContent(mail.NewContent(contentType, string(body)))
-	email.SetReplyTo(replyTo)
+	email := &mailer.Message{
+		From:    mailer.NewAddress(fromName, formConf.NoReplyEmail),
+		To:      []mailer.Address{mailer.NewAddress(form.Name, form.Email)},
+		ReplyTo: &replyTo,
+		Subject: subject,
+	}
+
+	email.Text, email.HTML, err = templates.executeAlternatives(templateName, formConf.ConfirmationTemplateFile, data)
+	if err != nil {
+		return nil, err
+	}
+	email.SendGrid = sendGridOptions(formConf, formConf.SendGridConfirmationTemplateId, int(formConf.SendGridAsmGroupId), subject, data)
 
 	return email, nil
 }
 
-func (service *sailService) createBodyFromTemplate(name string, form *EmailForm) ([]byte, error) {
-	buf := &bytes.Buffer{}
-	err := service.templates.ExecuteTemplate(buf, name, map[string]any{
+// createBodyFromTemplate returns the plain-text and HTML bodies rendered from the named template
+// and its counterpart, either of them is empty when there is no such template.
+func (service *sailService) createBodyFromTemplate(name string, data map[string]any) (string, string, error) {
+	templates, err := service.templates.load()
+	if err != nil {
+		return "", "", err
+	}
+	return templates.executeAlternatives(name, name, data)
+}
+
+// templateData returns the values available in the email templates and the subject and sender name templates.
+func templateData(formConf *config.Form, form *EmailForm) map[string]any {
+	// Declared fields are also available as FORM_<NAME>, for example FORM_PHONE_NUMBER for "phone-number".
+	// The other values are set afterwards, so that a field such as "id" can't replace FORM_ID.
+	data := make(map[string]any)
+	for name, value := range form.Fields {
+		data[fieldMacro(name)] = value
+	}
+	values := map[string]any{
 		"FORM_NAME":       form.Name,
 		"FORM_EMAIL":      form.Email,
 		"FORM_SUBJECT":    form.Subject,
 		"FORM_MESSAGE":    form.Message,
-		"NOREPLY_NAME":    service.env.NoReplyName,
-		"NOREPLY_EMAIL":   service.env.NoReplyEmail,
-		"RECIPIENT_NAME":  service.env.RecipientName,
-		"RECIPIENT_EMAIL": service.env.RecipientEmail,
-	})
-	if err != nil {
-		return nil, err
+		"FIELDS":          form.Fields,
+		"ALL_FIELDS":      form.AllFields,
+		"FORM_ID":         formConf.ID,
+		"SUBMISSION_ID":   form.SubmissionId,
+		"TIMESTAMP":       form.SubmittedAt,
+		"CLIENT_IP":       form.ClientIp,
+		"USER_AGENT":      form.UserAgent,
+		"REFERER":         form.Referer,
+		"NOREPLY_NAME":    formConf.NoReplyName,
+		"NOREPLY_EMAIL":   formConf.NoReplyEmail,
+		"RECIPIENT_NAME":  formConf.RecipientName,
+		"RECIPIENT_EMAIL": formConf.RecipientEmail,
+		"ASSIGNEE_NAME":   "",
+		"ASSIGNEE_EMAIL":  "",
+	}
+	if form.Assignee != nil {
+		values["ASSIGNEE_NAME"] = form.Assignee.Name
+		values["ASSIGNEE_EMAIL"] = form.Assignee.Email
 	}
-	return buf.Bytes(), nil
+	for key, value := range values {
+		data[key] = value
+	}
+	return data
 }
 
-func getContentType(data []byte) string {
-	contentType := http.DetectContentType(data)
-	if strings.HasPrefix(contentType, "text/html") {
-		return "text/html"
+// sendGridOptions returns the SendGrid features of the message, or nil when the form uses none of them.
+// Dynamic templates get the template values, together with the rendered subject as SUBJECT.
+func sendGridOptions(formConf *config.Form, templateId string, asmGroupId int, subject string, data map[string]any) *mailer.SendGridOptions {
+	if templateId == "" && asmGroupId == 0 && len(formConf.SendGridCategories) == 0 && len(formConf.SendGridCustomArgs) == 0 {
+		return nil
+	}
+	options := &mailer.SendGridOptions{
+		TemplateId: templateId,
+		Categories: formConf.SendGridCategories,
+		CustomArgs: formConf.SendGridCustomArgs,
+		AsmGroupId: asmGroupId,
+	}
+	if templateId != "" {
+		options.TemplateData = map[string]any{"SUBJECT": subject}
+		for key, value := range data {
+			options.TemplateData[key] = value
+		}
+	}
+	return options
+}
+
+// formSubject returns the submitted subject, forms without a subject field get a generic one.
+func formSubject(form *EmailForm) string {
+	if form.Subject != "" {
+		return form.Subject
+	}
+	if form.Name != "" {
+		return "New form submission from " + form.Name
 	}
-	return "text/plain"
+	return "New form submission"
+}
+
+func fieldMacro(name string) string {
+	macro := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+	return "FORM_" + strings.ToUpper(macro)
+}
+
+// formIdFromPath returns the form ID of paths ending with /f/{id}, such as /send-email/f/contact.
+func formIdFromPath(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if n := len(parts); n >= 2 && parts[n-2] == "f" {
+		return parts[n-1]
+	}
+	return ""
+}
+
+func newSubmissionId() string {
+	id := make([]byte, 12)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
 }
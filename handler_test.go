@@ -0,0 +1,191 @@
+package sail
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/discard"
+	"gopkg.in/yaml.v3"
+
+	"github.com/demianbucik/sail/config"
+	"github.com/demianbucik/sail/mailer"
+	"github.com/demianbucik/sail/utils"
+)
+
+func init() {
+	log.SetHandler(discard.Default)
+}
+
+// sentMessages records the messages instead of sending them, or fails with err when it's set.
+type sentMessages struct {
+	mu       sync.Mutex
+	messages []*mailer.Message
+	err      error
+}
+
+func (sent *sentMessages) Send(message *mailer.Message) error {
+	sent.mu.Lock()
+	defer sent.mu.Unlock()
+	if sent.err != nil {
+		return sent.err
+	}
+	sent.messages = append(sent.messages, message)
+	return nil
+}
+
+func (sent *sentMessages) fail(err error) {
+	sent.mu.Lock()
+	defer sent.mu.Unlock()
+	sent.err = err
+}
+
+func (sent *sentMessages) last() *mailer.Message {
+	sent.mu.Lock()
+	defer sent.mu.Unlock()
+	if len(sent.messages) == 0 {
+		return nil
+	}
+	return sent.messages[len(sent.messages)-1]
+}
+
+func (sent *sentMessages) count() int {
+	sent.mu.Lock()
+	defer sent.mu.Unlock()
+	return len(sent.messages)
+}
+
+// newTestService returns a service configured by example.env.yaml without reCAPTCHA, and with the
+// settings of the YAML document on top. Emails are recorded in the returned sentMessages.
+func newTestService(t *testing.T, settings string) (*sailService, *sentMessages) {
+	t.Helper()
+	base, err := os.ReadFile("example.env.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := config.ParseEnv(func(env *config.Environ) error {
+		if err := yaml.Unmarshal(base, env); err != nil {
+			return err
+		}
+		if err := yaml.Unmarshal([]byte(`RECAPTCHA_VERSION: "off"`), env); err != nil {
+			return err
+		}
+		return yaml.Unmarshal([]byte(settings), env)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service, err := newSailService(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := &sentMessages{}
+	service.emailClient = sent
+	return service, sent
+}
+
+// submit posts the URL encoded values as a client asking for a JSON response.
+func submit(service *sailService, values string, headers ...string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/send-email", strings.NewReader(values))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		request.Header.Set(headers[i], headers[i+1])
+	}
+	return serve(service, request)
+}
+
+func serve(service *sailService, request *http.Request) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	utils.LogAndRecoverMiddleware(service.ServeHTTP)(recorder, request)
+	return recorder
+}
+
+func TestSubmissionResponses(t *testing.T) {
+	service, _ := newTestService(t, `
+CONFIRMATION_MODE: "off"
+FORMS: |
+  - ID: sales
+    RECIPIENT_EMAIL: sales@mydomain.com
+`)
+	valid := `{"name": "Bob", "email": "bob@example.com", "subject": "Hi", "message": "Hello"}`
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		wantCode    int
+		wantErrors  map[string]string
+	}{
+		{"JSON", "/send-email", "application/json", valid, http.StatusOK, nil},
+		{"JSON with a charset", "/send-email/f/sales", "application/json; charset=utf-8", valid, http.StatusOK, nil},
+		{"form ID in the body", "/send-email", "application/json", `{"form-id": "sales", "name": "Bob", "email": "bob@example.com", "subject": "Hi", "message": "Hello"}`, http.StatusOK, nil},
+		{"urlencoded", "/send-email", "application/x-www-form-urlencoded", "name=Bob&email=bob@example.com&subject=Hi&message=Hello", http.StatusOK, nil},
+		{"invalid JSON", "/send-email", "application/json", `{"name": "Bob"`, http.StatusBadRequest, nil},
+		{"JSON array", "/send-email", "application/json", `[]`, http.StatusBadRequest, nil},
+		{"unknown form", "/send-email/f/support", "application/json", valid, http.StatusNotFound, nil},
+		{"invalid fields", "/send-email", "application/json", `{"name": "Bob", "email": "bob", "message": "Hello"}`, http.StatusUnprocessableEntity,
+			map[string]string{"email": "invalid email address", "subject": "required"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			request.Header.Set("Content-Type", tt.contentType)
+			request.Header.Set("Accept", "application/json")
+			resp := serve(service, request)
+			if resp.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d, body %s", resp.Code, tt.wantCode, resp.Body)
+			}
+
+			var body submissionResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			wantStatus := "ok"
+			if tt.wantCode >= 400 {
+				wantStatus = "error"
+			}
+			if body.Status != wantStatus || body.Code != tt.wantCode || body.SubmissionId == "" {
+				t.Errorf("unexpected response %+v", body)
+			}
+			if !reflect.DeepEqual(body.Errors, tt.wantErrors) {
+				t.Errorf("errors = %v, want %v", body.Errors, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestSubmissionRedirects(t *testing.T) {
+	service, sent := newTestService(t, `CONFIRMATION_MODE: "off"`)
+
+	tests := []struct {
+		name         string
+		body         string
+		sendErr      error
+		wantLocation string
+	}{
+		{"success", "name=Bob&email=bob@example.com&subject=Hi&message=Hello", nil, "http://localhost:8000/success.html"},
+		{"invalid form", "name=Bob", nil, "http://localhost:8000/error.html"},
+		{"sending failed", "name=Bob&email=bob@example.com&subject=Hi&message=Hello", errors.New("connection refused"),
+			"http://localhost:8000/error.html"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent.fail(tt.sendErr)
+			request := httptest.NewRequest(http.MethodPost, "/send-email", strings.NewReader(tt.body))
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			resp := serve(service, request)
+			if resp.Code != http.StatusSeeOther || resp.Header().Get("Location") != tt.wantLocation {
+				t.Errorf("status = %d, location %q, want a redirect to %s", resp.Code, resp.Header().Get("Location"), tt.wantLocation)
+			}
+		})
+	}
+}
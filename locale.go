@@ -0,0 +1,84 @@
+package sail
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/demianbucik/sail/config"
+)
+
+// langField selects the language of the confirmation, it takes precedence over the Accept-Language header.
+const langField = "lang"
+
+// preferredLanguages returns the lowercase language tags the visitor prefers, most preferred first.
+// Regional tags are followed by their base language, for example "de-ch" by "de". Invalid tags are ignored,
+// because the tags are used in template names.
+func preferredLanguages(lang, acceptLanguage string) []string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+	var tags []weighted
+	if lang = strings.TrimSpace(lang); lang != "" {
+		tags = append(tags, weighted{tag: lang, quality: 2})
+	}
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if tag = strings.TrimSpace(tag); tag == "" || tag == "*" || quality <= 0 {
+			continue
+		}
+		tags = append(tags, weighted{tag: tag, quality: quality})
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].quality > tags[j].quality
+	})
+
+	var languages []string
+	seen := make(map[string]bool)
+	add := func(tag string) {
+		if !seen[tag] {
+			seen[tag] = true
+			languages = append(languages, tag)
+		}
+	}
+	for _, t := range tags {
+		tag := strings.ToLower(strings.ReplaceAll(t.tag, "_", "-"))
+		if !config.IsLanguageTag(tag) {
+			continue
+		}
+		add(tag)
+		if base, _, ok := strings.Cut(tag, "-"); ok {
+			add(base)
+		}
+	}
+	return languages
+}
+
+// localizedName returns the name of the localized template, for example "confirmation.de.html" for "confirmation.html".
+func localizedName(name, lang string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + lang + ext
+}
+
+// confirmationLanguage returns the first preferred language with a localized confirmation template or subject,
+// or an empty string when the defaults should be used.
+func confirmationLanguage(templates *templateSet, formConf *config.Form, languages []string) string {
+	for _, lang := range languages {
+		if templates.has(localizedName(formConf.ConfirmationTemplateFile, lang)) {
+			return lang
+		}
+		if _, ok := formConf.ConfirmationSubjects[lang]; ok {
+			return lang
+		}
+	}
+	return ""
+}
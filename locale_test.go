@@ -0,0 +1,90 @@
+package sail
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPreferredLanguages(t *testing.T) {
+	tests := []struct {
+		name           string
+		lang           string
+		acceptLanguage string
+		want           []string
+	}{
+		{"none", "", "", nil},
+		{"header order", "", "de-CH, fr;q=0.8, en;q=0.9", []string{"de-ch", "de", "en", "fr"}},
+		{"field first", "sl", "de, en;q=0.5", []string{"sl", "de", "en"}},
+		{"underscores and duplicates", "pt_BR", "pt-br, pt;q=0.9", []string{"pt-br", "pt"}},
+		{"wildcard and zero quality", "", "*, de;q=0, en;q=0.1", []string{"en"}},
+		{"invalid quality", "", "de;q=high, en", []string{"en"}},
+		{"invalid tags", "../de", "de.html, en", []string{"en"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := preferredLanguages(tt.lang, tt.acceptLanguage); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("preferredLanguages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalizedConfirmation(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"example_confirmation.de.html": "<p>Danke, {{ .FORM_NAME }}</p>",
+		"example_confirmation.de.txt":  "Danke, {{ .FORM_NAME }}",
+		"example_confirmation.sl.html": "<p>Hvala, {{ .FORM_NAME }}</p>",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	service, sent := newTestService(t, `
+TEMPLATES_DIR: "`+dir+`"
+CONFIRMATION_SUBJECTS: {fr: "Merci", de-at: "Servus"}
+`)
+
+	tests := []struct {
+		name           string
+		lang           string
+		acceptLanguage string
+		wantSubject    string
+		wantText       string
+		wantHTML       string
+	}{
+		{"default", "", "", "Thanks for contacting Bob Stone", "Hi Bob", "Hi Bob"},
+		{"localized template", "", "de", "Thanks for contacting Bob Stone", "Danke, Bob", "Danke, Bob"},
+		{"base language", "", "de-DE", "Thanks for contacting Bob Stone", "Danke, Bob", "Danke, Bob"},
+		{"localized subject", "", "fr, de;q=0.5", "Merci", "Hi Bob", "Hi Bob"},
+		// The first language with a localized template or subject is used for both.
+		{"regional subject", "de-AT", "", "Servus", "Hi Bob", "Hi Bob"},
+		{"field over header", "de", "fr", "Thanks for contacting Bob Stone", "Danke, Bob", "Danke, Bob"},
+		{"unknown language", "", "it", "Thanks for contacting Bob Stone", "Hi Bob", "Hi Bob"},
+		// The localized HTML template is paired with the default plain-text template.
+		{"localized HTML only", "sl", "", "Thanks for contacting Bob Stone", "Hi Bob", "Hvala, Bob"},
+		{"invalid field", "../sl", "de", "Thanks for contacting Bob Stone", "Danke, Bob", "Danke, Bob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := url.Values{
+				"name": {"Bob"}, "email": {"bob@example.com"}, "subject": {"Hi"}, "message": {"Hello"}, "lang": {tt.lang},
+			}
+			if resp := submit(service, values.Encode(), "Accept-Language", tt.acceptLanguage); resp.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", resp.Code, resp.Body)
+			}
+			confirmation := sent.last()
+			if confirmation.Subject != tt.wantSubject || !strings.Contains(confirmation.Text, tt.wantText) {
+				t.Errorf("confirmation %q with text %q, want %q with %q", confirmation.Subject, confirmation.Text, tt.wantSubject, tt.wantText)
+			}
+			if !strings.Contains(confirmation.HTML, tt.wantHTML) {
+				t.Errorf("confirmation HTML %q, want %q", confirmation.HTML, tt.wantHTML)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package mailer
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitBreaker opens after a number of consecutive failures and stays open for the cool-down period.
+// Once the cool-down elapses a single trial call is allowed, its outcome closes or reopens the breaker.
+// A zero threshold disables the breaker.
+type CircuitBreaker struct {
+	Threshold int
+	CoolDown  time.Duration
+
+	// now returns the current time, tests replace it.
+	now func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	lastErr   error
+}
+
+func (cb *CircuitBreaker) Allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.Threshold <= 0 || cb.failures < cb.Threshold {
+		return true
+	}
+	now := cb.clock()
+	if now.Before(cb.openUntil) {
+		return false
+	}
+	// Half-open, block other callers until the trial call finishes.
+	cb.openUntil = now.Add(cb.CoolDown)
+	return true
+}
+
+// Success records a call the provider answered, which closes the breaker.
+func (cb *CircuitBreaker) Success() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures = 0
+	cb.lastErr = nil
+}
+
+// Failure records a failed call and reports whether the breaker has just opened.
+func (cb *CircuitBreaker) Failure(err error) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures++
+	cb.lastErr = err
+	if cb.Threshold <= 0 || cb.failures < cb.Threshold {
+		return false
+	}
+	cb.openUntil = cb.clock().Add(cb.CoolDown)
+	return cb.failures == cb.Threshold
+}
+
+type Health struct {
+	Failures  int       `json:"failures"`
+	Open      bool      `json:"open"`
+	OpenUntil time.Time `json:"openUntil"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+func (cb *CircuitBreaker) Health() Health {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	health := Health{Failures: cb.failures}
+	if cb.Threshold > 0 && cb.failures >= cb.Threshold {
+		health.Open = cb.clock().Before(cb.openUntil)
+		health.OpenUntil = cb.openUntil
+	}
+	if cb.lastErr != nil {
+		health.LastError = cb.lastErr.Error()
+	}
+	return health
+}
+
+func (cb *CircuitBreaker) clock() time.Time {
+	if cb.now == nil {
+		return time.Now()
+	}
+	return cb.now()
+}
@@ -0,0 +1,94 @@
+package mailer
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced time source for circuit breakers.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestBreaker(clock *fakeClock) *CircuitBreaker {
+	return &CircuitBreaker{Threshold: 2, CoolDown: time.Minute, now: clock.Now}
+}
+
+func TestCircuitBreakerOpens(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	cb := newTestBreaker(clock)
+
+	if opened := cb.Failure(errors.New("timeout")); opened {
+		t.Error("breaker should not open before the threshold")
+	}
+	if !cb.Allow() {
+		t.Error("breaker should allow calls before the threshold")
+	}
+	if opened := cb.Failure(errors.New("timeout")); !opened {
+		t.Error("breaker should report that it opened at the threshold")
+	}
+	if cb.Allow() {
+		t.Error("open breaker should not allow calls")
+	}
+
+	health := cb.Health()
+	if !health.Open || health.Failures != 2 || health.LastError != "timeout" || !health.OpenUntil.Equal(clock.now.Add(time.Minute)) {
+		t.Errorf("unexpected health %+v", health)
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcome  func(cb *CircuitBreaker)
+		wantOpen bool
+	}{
+		{"trial succeeds", func(cb *CircuitBreaker) { cb.Success() }, false},
+		{"trial fails", func(cb *CircuitBreaker) { cb.Failure(errors.New("timeout")) }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Unix(0, 0)}
+			cb := newTestBreaker(clock)
+			cb.Failure(errors.New("timeout"))
+			cb.Failure(errors.New("timeout"))
+
+			clock.Advance(time.Minute)
+			if !cb.Allow() {
+				t.Fatal("breaker should allow a trial call after the cool-down")
+			}
+			if cb.Allow() {
+				t.Fatal("breaker should block other calls during the trial call")
+			}
+
+			tt.outcome(cb)
+			if got := !cb.Allow(); got != tt.wantOpen {
+				t.Errorf("open after the trial = %v, want %v", got, tt.wantOpen)
+			}
+			if got := cb.Health().Open; got != tt.wantOpen {
+				t.Errorf("Health().Open = %v, want %v", got, tt.wantOpen)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	cb := &CircuitBreaker{}
+	for i := 0; i < 5; i++ {
+		if cb.Failure(errors.New("timeout")) {
+			t.Fatal("disabled breaker should never open")
+		}
+	}
+	if !cb.Allow() {
+		t.Error("disabled breaker should allow calls")
+	}
+}
@@ -0,0 +1,27 @@
+package mailer
+
+import (
+	"errors"
+	"net/http"
+	"net/textproto"
+)
+
+// IsTransient reports whether a failed send might succeed when retried, possibly with another provider.
+// Rejections of the message itself, such as invalid addresses or authentication failures, are permanent.
+func IsTransient(err error) bool {
+	var failoverErr *FailoverError
+	if errors.As(err, &failoverErr) {
+		return failoverErr.Transient()
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		code := statusErr.StatusCode
+		return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500
+	}
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return smtpErr.Code >= 400 && smtpErr.Code < 500
+	}
+	// Network failures, timeouts and similar.
+	return err != nil
+}
@@ -0,0 +1,95 @@
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Failover sends through the first healthy provider of an ordered chain.
+// Transient failures fall through to the next provider, permanent failures stop the chain.
+// A permanent failure still means the provider is reachable, so it counts as a success for its circuit breaker.
+type Failover struct {
+	Threshold int
+	CoolDown  time.Duration
+	// OnOpen is called when a provider's circuit breaker opens, it can be used for logging.
+	OnOpen func(provider Provider, err error)
+
+	providers []failoverProvider
+}
+
+type failoverProvider struct {
+	name    Provider
+	mailer  Mailer
+	breaker *CircuitBreaker
+}
+
+func (f *Failover) Add(name Provider, m Mailer) {
+	f.providers = append(f.providers, failoverProvider{
+		name:    name,
+		mailer:  m,
+		breaker: &CircuitBreaker{Threshold: f.Threshold, CoolDown: f.CoolDown},
+	})
+}
+
+// FailoverError holds the errors of every provider in the chain. Whether the send might succeed when retried
+// depends only on the last provider that was tried, a 503 of the first provider doesn't make the 400 of the
+// second one transient.
+type FailoverError struct {
+	Errs []error
+	// last is the error of the last provider that was tried, nil when every circuit breaker was open.
+	last error
+}
+
+func (err *FailoverError) Error() string {
+	return errors.Join(err.Errs...).Error()
+}
+
+func (err *FailoverError) Unwrap() []error {
+	return err.Errs
+}
+
+// Transient reports whether the last provider that was tried failed with a transient error.
+// When every circuit breaker was open, the providers may be tried again after the cool-down.
+func (err *FailoverError) Transient() bool {
+	return err.last == nil || IsTransient(err.last)
+}
+
+func (f *Failover) Send(message *Message) error {
+	failoverErr := &FailoverError{}
+	for _, provider := range f.providers {
+		if !provider.breaker.Allow() {
+			failoverErr.Errs = append(failoverErr.Errs, fmt.Errorf("%s: circuit breaker open", provider.name))
+			continue
+		}
+
+		err := provider.mailer.Send(message)
+		if err == nil {
+			provider.breaker.Success()
+			return nil
+		}
+		failoverErr.Errs = append(failoverErr.Errs, fmt.Errorf("%s: %w", provider.name, err))
+		failoverErr.last = err
+
+		if !IsTransient(err) {
+			provider.breaker.Success()
+			break
+		}
+		if provider.breaker.Failure(err) && f.OnOpen != nil {
+			f.OnOpen(provider.name, err)
+		}
+	}
+	if len(failoverErr.Errs) == 0 {
+		return errors.New("no email providers configured")
+	}
+	return failoverErr
+}
+
+// Health returns the state of every provider in the chain.
+func (f *Failover) Health() map[Provider]Health {
+	health := make(map[Provider]Health, len(f.providers))
+	for _, provider := range f.providers {
+		health[provider.name] = provider.breaker.Health()
+	}
+	return health
+}
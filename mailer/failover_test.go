@@ -0,0 +1,183 @@
+package mailer
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubMailer returns the queued errors one by one, then nil, and counts its calls.
+type stubMailer struct {
+	errs  []error
+	calls int
+}
+
+func (m *stubMailer) Send(*Message) error {
+	m.calls++
+	if len(m.errs) == 0 {
+		return nil
+	}
+	err := m.errs[0]
+	m.errs = m.errs[1:]
+	return err
+}
+
+var (
+	errTransient = &StatusError{StatusCode: http.StatusServiceUnavailable}
+	errPermanent = &StatusError{StatusCode: http.StatusUnprocessableEntity}
+)
+
+func TestFailoverOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		first     []error
+		second    []error
+		wantErr   bool
+		wantCalls [2]int
+	}{
+		{"first succeeds", nil, nil, false, [2]int{1, 0}},
+		{"transient falls through", []error{errTransient}, nil, false, [2]int{1, 1}},
+		{"permanent stops the chain", []error{errPermanent}, nil, true, [2]int{1, 0}},
+		{"all fail", []error{errTransient}, []error{errTransient}, true, [2]int{1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, second := &stubMailer{errs: tt.first}, &stubMailer{errs: tt.second}
+			f := &Failover{}
+			f.Add(SendGridProvider, first)
+			f.Add(SMTPProvider, second)
+
+			err := f.Send(testMessage())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Send() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := [2]int{first.calls, second.calls}; got != tt.wantCalls {
+				t.Errorf("calls = %v, want %v", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestFailoverNoProviders(t *testing.T) {
+	if err := (&Failover{}).Send(testMessage()); err == nil {
+		t.Error("Send() without providers should fail")
+	}
+}
+
+func TestFailoverSkipsOpenBreaker(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	first, second := &stubMailer{errs: []error{errTransient, errTransient}}, &stubMailer{}
+	var opened []Provider
+	f := &Failover{
+		Threshold: 2,
+		CoolDown:  time.Minute,
+		OnOpen:    func(provider Provider, err error) { opened = append(opened, provider) },
+	}
+	f.Add(SendGridProvider, first)
+	f.Add(SMTPProvider, second)
+	f.providers[0].breaker.now = clock.Now
+
+	for i := 0; i < 3; i++ {
+		if err := f.Send(testMessage()); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if first.calls != 2 || second.calls != 3 {
+		t.Errorf("calls = %d, %d, want 2, 3", first.calls, second.calls)
+	}
+	if len(opened) != 1 || opened[0] != SendGridProvider {
+		t.Errorf("OnOpen calls = %v", opened)
+	}
+	if !f.Health()[SendGridProvider].Open {
+		t.Error("first provider should be open")
+	}
+
+	// After the cool-down the trial call succeeds and the first provider is used again.
+	clock.Advance(time.Minute)
+	if err := f.Send(testMessage()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if first.calls != 3 || second.calls != 3 || f.Health()[SendGridProvider].Open {
+		t.Errorf("trial call should close the breaker, calls = %d, %d", first.calls, second.calls)
+	}
+}
+
+func TestFailoverPermanentTrialClosesBreaker(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	first := &stubMailer{errs: []error{errTransient, errTransient, errPermanent}}
+	f := &Failover{Threshold: 2, CoolDown: time.Minute}
+	f.Add(SendGridProvider, first)
+	f.providers[0].breaker.now = clock.Now
+
+	_ = f.Send(testMessage())
+	_ = f.Send(testMessage())
+	if !f.Health()[SendGridProvider].Open {
+		t.Fatal("breaker should be open")
+	}
+
+	clock.Advance(time.Minute)
+	err := f.Send(testMessage())
+	if err == nil || !strings.Contains(err.Error(), "422") {
+		t.Fatalf("Send() error = %v, want the permanent error", err)
+	}
+	// The provider answered the trial call, so the breaker closes.
+	if health := f.Health()[SendGridProvider]; health.Open || health.Failures != 0 {
+		t.Errorf("unexpected health %+v", health)
+	}
+	if err = f.Send(testMessage()); err != nil || first.calls != 4 {
+		t.Errorf("Send() error = %v, calls = %d", err, first.calls)
+	}
+}
+
+func TestFailoverErrorTransient(t *testing.T) {
+	tests := []struct {
+		name          string
+		first, second error
+		openFirst     bool
+		openSecond    bool
+		wantTransient bool
+	}{
+		{"transient then permanent", errTransient, errPermanent, false, false, false},
+		{"transient then transient", errTransient, errTransient, false, false, true},
+		{"permanent stops the chain", errPermanent, errTransient, false, false, false},
+		{"transient then open breaker", errTransient, nil, false, true, true},
+		{"open breaker then permanent", nil, errPermanent, true, false, false},
+		{"all breakers open", nil, nil, true, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Failover{Threshold: 1, CoolDown: time.Minute}
+			f.Add(SendGridProvider, &stubMailer{errs: []error{tt.first}})
+			f.Add(SMTPProvider, &stubMailer{errs: []error{tt.second}})
+			if tt.openFirst {
+				f.providers[0].breaker.Failure(errTransient)
+			}
+			if tt.openSecond {
+				f.providers[1].breaker.Failure(errTransient)
+			}
+
+			err := f.Send(testMessage())
+			var failoverErr *FailoverError
+			if !errors.As(err, &failoverErr) {
+				t.Fatalf("Send() error = %v, want a FailoverError", err)
+			}
+			if IsTransient(err) != tt.wantTransient {
+				t.Errorf("IsTransient(%v) = %v, want %v", err, !tt.wantTransient, tt.wantTransient)
+			}
+		})
+	}
+}
+
+func TestFailoverErrorsJoined(t *testing.T) {
+	f := &Failover{}
+	f.Add(SendGridProvider, &stubMailer{errs: []error{errTransient}})
+	f.Add(SMTPProvider, &stubMailer{errs: []error{errPermanent}})
+
+	err := f.Send(testMessage())
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || !strings.Contains(err.Error(), "sendgrid: ") || !strings.Contains(err.Error(), "smtp: ") {
+		t.Errorf("Send() error = %v", err)
+	}
+}
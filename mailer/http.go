@@ -0,0 +1,32 @@
+package mailer
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// StatusError is returned when a provider API responds with an unsuccessful status code.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (err *StatusError) Error() string {
+	return fmt.Sprintf("response code '%d' not ok, body '%s'", err.StatusCode, err.Body)
+}
+
+func doRequest(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
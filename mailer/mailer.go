@@ -0,0 +1,67 @@
+//go:generate mockery --inpackage --name=Mailer
+
+package mailer
+
+import (
+	"net/mail"
+)
+
+type Provider string
+
+const (
+	SendGridProvider Provider = "sendgrid"
+	SMTPProvider     Provider = "smtp"
+	MailgunProvider  Provider = "mailgun"
+	PostmarkProvider Provider = "postmark"
+	SESProvider      Provider = "ses"
+)
+
+// Mailer delivers a provider-neutral message through a concrete email backend.
+type Mailer interface {
+	Send(message *Message) error
+}
+
+type Address struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email"`
+}
+
+func NewAddress(name, email string) Address {
+	return Address{Name: name, Email: email}
+}
+
+// String formats the address for use in message headers, for example "Bob Stone <bob@example.com>".
+func (addr Address) String() string {
+	return (&mail.Address{Name: addr.Name, Address: addr.Email}).String()
+}
+
+type Message struct {
+	From    Address   `json:"from"`
+	To      []Address `json:"to"`
+	Cc      []Address `json:"cc,omitempty"`
+	Bcc     []Address `json:"bcc,omitempty"` // Not listed in the message headers
+	ReplyTo *Address  `json:"replyTo,omitempty"`
+	Subject string    `json:"subject"`
+	// At least one of the bodies should be set.
+	Text string `json:"text,omitempty"`
+	HTML string `json:"html,omitempty"`
+
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	// SendGrid options are ignored by the other providers.
+	SendGrid *SendGridOptions `json:"sendGrid,omitempty"`
+}
+
+// Recipients returns the To, Cc and Bcc addresses.
+func (message *Message) Recipients() []Address {
+	recipients := make([]Address, 0, len(message.To)+len(message.Cc)+len(message.Bcc))
+	recipients = append(recipients, message.To...)
+	recipients = append(recipients, message.Cc...)
+	return append(recipients, message.Bcc...)
+}
+
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Data        []byte `json:"data"`
+}
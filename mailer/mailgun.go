@@ -0,0 +1,89 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+const MailgunBaseURL = "https://api.mailgun.net"
+
+type Mailgun struct {
+	Client http.Client
+	// BaseURL can be changed to the EU region endpoint "https://api.eu.mailgun.net".
+	BaseURL string
+	Domain  string
+	ApiKey  string
+}
+
+func (m *Mailgun) Send(message *Message) error {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	fields := [][2]string{
+		{"from", message.From.String()},
+		{"subject", message.Subject},
+	}
+	for _, to := range message.To {
+		fields = append(fields, [2]string{"to", to.String()})
+	}
+	for _, cc := range message.Cc {
+		fields = append(fields, [2]string{"cc", cc.String()})
+	}
+	for _, bcc := range message.Bcc {
+		fields = append(fields, [2]string{"bcc", bcc.String()})
+	}
+	if message.ReplyTo != nil {
+		fields = append(fields, [2]string{"h:Reply-To", message.ReplyTo.String()})
+	}
+	if message.Text != "" {
+		fields = append(fields, [2]string{"text", message.Text})
+	}
+	if message.HTML != "" {
+		fields = append(fields, [2]string{"html", message.HTML})
+	}
+	for _, field := range fields {
+		if err := writer.WriteField(field[0], field[1]); err != nil {
+			return err
+		}
+	}
+	for _, attachment := range message.Attachments {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{
+			"name":     "attachment",
+			"filename": attachment.Filename,
+		}))
+		header.Set("Content-Type", attachment.ContentType)
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		if _, err = part.Write(attachment.Data); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/v3/%s/messages", strings.TrimSuffix(m.baseURL(), "/"), m.Domain)
+	req, err := http.NewRequest(http.MethodPost, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.SetBasicAuth("api", m.ApiKey)
+
+	return doRequest(&m.Client, req)
+}
+
+func (m *Mailgun) baseURL() string {
+	if m.BaseURL == "" {
+		return MailgunBaseURL
+	}
+	return m.BaseURL
+}
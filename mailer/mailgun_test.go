@@ -0,0 +1,59 @@
+package mailer
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestMailgunSend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v3/mg.example.com/messages" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if user, password, ok := r.BasicAuth(); !ok || user != "api" || password != "key" {
+			t.Errorf("unexpected basic auth %s:%s", user, password)
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+
+		want := map[string][]string{
+			"from":       {`"My Website" <noreply@example.com>`},
+			"to":         {`"Bob Stone" <bob@example.com>`},
+			"cc":         {"<cc@example.com>"},
+			"bcc":        {"<bcc@example.com>"},
+			"h:Reply-To": {`"Ann Lee" <ann@example.com>`},
+			"subject":    {"Hello"},
+			"text":       {"Hello, Bob"},
+			"html":       {"<p>Hello, Bob</p>"},
+		}
+		if !reflect.DeepEqual(map[string][]string(r.MultipartForm.Value), want) {
+			t.Errorf("form values = %v\nwant %v", r.MultipartForm.Value, want)
+		}
+
+		files := r.MultipartForm.File["attachment"]
+		if len(files) != 1 || files[0].Filename != "notes.txt" || files[0].Header.Get("Content-Type") != "text/plain" {
+			t.Fatalf("unexpected attachments %v", files)
+		}
+		file, _ := files[0].Open()
+		data, _ := io.ReadAll(file)
+		if string(data) != "notes" {
+			t.Errorf("attachment data = %q", data)
+		}
+	}))
+	defer server.Close()
+
+	m := &Mailgun{BaseURL: server.URL + "/", Domain: "mg.example.com", ApiKey: "key"}
+	if err := m.Send(testMessage()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMailgunSendErrors(t *testing.T) {
+	testStatusErrors(t, func(url string) Mailer {
+		return &Mailgun{BaseURL: url, Domain: "mg.example.com", ApiKey: "key"}
+	})
+}
@@ -0,0 +1,178 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// BuildMIME encodes the message as an RFC 5322 message with MIME bodies. Bcc recipients are left out of the headers.
+// Messages with both text and HTML bodies are sent as multipart/alternative,
+// messages with attachments are wrapped in multipart/mixed.
+func BuildMIME(message *Message) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("From", message.From.String())
+	header.Set("To", joinAddresses(message.To))
+	if len(message.Cc) > 0 {
+		header.Set("Cc", joinAddresses(message.Cc))
+	}
+	if message.ReplyTo != nil {
+		header.Set("Reply-To", message.ReplyTo.String())
+	}
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", message.Subject))
+	header.Set("Date", time.Now().Format(time.RFC1123Z))
+	header.Set("Message-ID", newMessageID(message.From.Email))
+	header.Set("MIME-Version", "1.0")
+
+	bodyHeader, body, err := buildBody(message)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(message.Attachments) == 0 {
+		for key, values := range bodyHeader {
+			header[key] = values
+		}
+		writeHeader(buf, header)
+		buf.Write(body)
+		return buf.Bytes(), nil
+	}
+
+	writer := multipart.NewWriter(buf)
+	header.Set("Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": writer.Boundary()}))
+	writeHeader(buf, header)
+
+	part, err := writer.CreatePart(bodyHeader)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = part.Write(body); err != nil {
+		return nil, err
+	}
+	for _, attachment := range message.Attachments {
+		if err = writeAttachment(writer, attachment); err != nil {
+			return nil, err
+		}
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// buildBody returns the headers and the encoded content of the text and HTML bodies.
+func buildBody(message *Message) (textproto.MIMEHeader, []byte, error) {
+	buf := &bytes.Buffer{}
+	header := make(textproto.MIMEHeader)
+
+	if message.Text != "" && message.HTML != "" {
+		writer := multipart.NewWriter(buf)
+		header.Set("Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": writer.Boundary()}))
+
+		if err := writePart(writer, "text/plain", message.Text); err != nil {
+			return nil, nil, err
+		}
+		if err := writePart(writer, "text/html", message.HTML); err != nil {
+			return nil, nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, nil, err
+		}
+		return header, buf.Bytes(), nil
+	}
+
+	contentType, body := "text/plain", message.Text
+	if message.HTML != "" {
+		contentType, body = "text/html", message.HTML
+	}
+	header.Set("Content-Type", contentType+"; charset=utf-8")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	if err := writeQuotedPrintable(buf, body); err != nil {
+		return nil, nil, err
+	}
+	return header, buf.Bytes(), nil
+}
+
+func writePart(writer *multipart.Writer, contentType, body string) error {
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Type", contentType+"; charset=utf-8")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	return writeQuotedPrintable(part, body)
+}
+
+func writeAttachment(writer *multipart.Writer, attachment Attachment) error {
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Type", mime.FormatMediaType(attachment.ContentType, map[string]string{"name": attachment.Filename}))
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	header.Set("Content-Transfer-Encoding", "base64")
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	// Lines of base64 encoded content must not be longer than 76 characters.
+	encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+	for len(encoded) > 76 {
+		if _, err = io.WriteString(part, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err = io.WriteString(part, encoded+"\r\n")
+	return err
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qpWriter := quotedprintable.NewWriter(w)
+	if _, err := qpWriter.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qpWriter.Close()
+}
+
+func writeHeader(buf *bytes.Buffer, header textproto.MIMEHeader) {
+	// Keep a stable, conventional order instead of ranging over the map.
+	for _, key := range []string{
+		"From", "To", "Cc", "Reply-To", "Subject", "Date", "Message-ID",
+		"MIME-Version", "Content-Type", "Content-Transfer-Encoding",
+	} {
+		if value := header.Get(key); value != "" {
+			fmt.Fprintf(buf, "%s: %s\r\n", key, value)
+		}
+	}
+	buf.WriteString("\r\n")
+}
+
+func joinAddresses(addrs []Address) string {
+	formatted := make([]string, len(addrs))
+	for i, addr := range addrs {
+		formatted[i] = addr.String()
+	}
+	return strings.Join(formatted, ", ")
+}
+
+func newMessageID(fromEmail string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(fromEmail, "@"); i >= 0 {
+		domain = fromEmail[i+1:]
+	}
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(id), domain)
+}
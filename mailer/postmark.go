@@ -0,0 +1,83 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const PostmarkBaseURL = "https://api.postmarkapp.com"
+
+type Postmark struct {
+	Client        http.Client
+	BaseURL       string
+	ServerToken   string
+	MessageStream string
+}
+
+type postmarkEmail struct {
+	From          string `json:"From"`
+	To            string `json:"To"`
+	Cc            string `json:"Cc,omitempty"`
+	Bcc           string `json:"Bcc,omitempty"`
+	ReplyTo       string `json:"ReplyTo,omitempty"`
+	Subject       string `json:"Subject"`
+	TextBody      string `json:"TextBody,omitempty"`
+	HtmlBody      string `json:"HtmlBody,omitempty"`
+	MessageStream string `json:"MessageStream,omitempty"`
+
+	Attachments []postmarkAttachment `json:"Attachments,omitempty"`
+}
+
+type postmarkAttachment struct {
+	Name        string `json:"Name"`
+	Content     []byte `json:"Content"`
+	ContentType string `json:"ContentType"`
+}
+
+func (m *Postmark) Send(message *Message) error {
+	email := postmarkEmail{
+		From:          message.From.String(),
+		To:            joinAddresses(message.To),
+		Cc:            joinAddresses(message.Cc),
+		Bcc:           joinAddresses(message.Bcc),
+		Subject:       message.Subject,
+		TextBody:      message.Text,
+		HtmlBody:      message.HTML,
+		MessageStream: m.MessageStream,
+	}
+	if message.ReplyTo != nil {
+		email.ReplyTo = message.ReplyTo.String()
+	}
+	for _, attachment := range message.Attachments {
+		email.Attachments = append(email.Attachments, postmarkAttachment{
+			Name:        attachment.Filename,
+			Content:     attachment.Data,
+			ContentType: attachment.ContentType,
+		})
+	}
+
+	body, err := json.Marshal(email)
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimSuffix(m.baseURL(), "/") + "/email"
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Postmark-Server-Token", m.ServerToken)
+
+	return doRequest(&m.Client, req)
+}
+
+func (m *Postmark) baseURL() string {
+	if m.BaseURL == "" {
+		return PostmarkBaseURL
+	}
+	return m.BaseURL
+}
@@ -0,0 +1,53 @@
+package mailer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestPostmarkSend(t *testing.T) {
+	var got postmarkEmail
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/email" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if token := r.Header.Get("X-Postmark-Server-Token"); token != "token" {
+			t.Errorf("X-Postmark-Server-Token = %s", token)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(`{"ErrorCode": 0, "Message": "OK"}`))
+	}))
+	defer server.Close()
+
+	m := &Postmark{BaseURL: server.URL, ServerToken: "token", MessageStream: "outbound"}
+	if err := m.Send(testMessage()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := postmarkEmail{
+		From:          `"My Website" <noreply@example.com>`,
+		To:            `"Bob Stone" <bob@example.com>`,
+		Cc:            "<cc@example.com>",
+		Bcc:           "<bcc@example.com>",
+		ReplyTo:       `"Ann Lee" <ann@example.com>`,
+		Subject:       "Hello",
+		TextBody:      "Hello, Bob",
+		HtmlBody:      "<p>Hello, Bob</p>",
+		MessageStream: "outbound",
+		Attachments:   []postmarkAttachment{{Name: "notes.txt", Content: []byte("notes"), ContentType: "text/plain"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("request body = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestPostmarkSendErrors(t *testing.T) {
+	testStatusErrors(t, func(url string) Mailer {
+		return &Postmark{BaseURL: url, ServerToken: "token"}
+	})
+}
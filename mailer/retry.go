@@ -0,0 +1,30 @@
+package mailer
+
+import (
+	"time"
+
+	"github.com/demianbucik/sail/utils"
+)
+
+type retrying struct {
+	Mailer
+	retries int
+	backOff time.Duration
+}
+
+// WithRetry retries transient failures with an exponential back-off, permanent failures are returned immediately.
+func WithRetry(m Mailer, retries int, backOff time.Duration) Mailer {
+	return &retrying{Mailer: m, retries: retries, backOff: backOff}
+}
+
+func (m *retrying) Send(message *Message) error {
+	var err error
+	utils.Retry(m.retries, m.backOff, func() error {
+		err = m.Mailer.Send(message)
+		if IsTransient(err) {
+			return err
+		}
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,118 @@
+//go:generate mockery --inpackage --name=SendGridClient
+
+package mailer
+
+import (
+	"encoding/base64"
+
+	"github.com/sendgrid/rest"
+	"github.com/sendgrid/sendgrid-go"
+	"github.com/sendgrid/sendgrid-go/helpers/mail"
+)
+
+type SendGridClient interface {
+	Send(email *mail.SGMailV3) (*rest.Response, error)
+}
+
+type SendGrid struct {
+	Client SendGridClient
+	// SandboxMode validates the messages without delivering them.
+	SandboxMode bool
+}
+
+// SendGridOptions are the SendGrid features without an equivalent in the other providers.
+type SendGridOptions struct {
+	// TemplateId selects a dynamic template, which replaces the subject and the bodies of the message.
+	TemplateId   string         `json:"templateId,omitempty"`
+	TemplateData map[string]any `json:"templateData,omitempty"`
+
+	Categories []string          `json:"categories,omitempty"`
+	CustomArgs map[string]string `json:"customArgs,omitempty"`
+	// AsmGroupId is the unsubscribe group of the message.
+	AsmGroupId int `json:"asmGroupId,omitempty"`
+}
+
+func NewSendGrid(apiKey string) *SendGrid {
+	return &SendGrid{
+		Client: sendgrid.NewSendClient(apiKey),
+	}
+}
+
+func (m *SendGrid) Send(message *Message) error {
+	email := newSGMail(message)
+	if m.SandboxMode {
+		email.SetMailSettings(mail.NewMailSettings().SetSandboxMode(mail.NewSetting(true)))
+	}
+	resp, err := m.Client.Send(email)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		return &StatusError{StatusCode: resp.StatusCode, Body: resp.Body}
+	}
+	return nil
+}
+
+func newSGMail(message *Message) *mail.SGMailV3 {
+	email := mail.NewV3Mail()
+	email.SetFrom(newSGEmail(message.From))
+	email.Subject = message.Subject
+
+	personalization := mail.NewPersonalization()
+	for _, to := range message.To {
+		personalization.AddTos(newSGEmail(to))
+	}
+	for _, cc := range message.Cc {
+		personalization.AddCCs(newSGEmail(cc))
+	}
+	for _, bcc := range message.Bcc {
+		personalization.AddBCCs(newSGEmail(bcc))
+	}
+	email.AddPersonalizations(personalization)
+
+	options := message.SendGrid
+	if options == nil {
+		options = &SendGridOptions{}
+	}
+	if options.TemplateId != "" {
+		email.SetTemplateID(options.TemplateId)
+		for key, value := range options.TemplateData {
+			personalization.SetDynamicTemplateData(key, value)
+		}
+	} else {
+		// SendGrid requires the plain text content to precede the HTML content.
+		if message.Text != "" {
+			email.AddContent(mail.NewContent("text/plain", message.Text))
+		}
+		if message.HTML != "" {
+			email.AddContent(mail.NewContent("text/html", message.HTML))
+		}
+	}
+	if len(options.Categories) > 0 {
+		email.AddCategories(options.Categories...)
+	}
+	for key, value := range options.CustomArgs {
+		email.SetCustomArg(key, value)
+	}
+	if options.AsmGroupId != 0 {
+		email.SetASM(mail.NewASM().SetGroupID(options.AsmGroupId))
+	}
+
+	if message.ReplyTo != nil {
+		email.SetReplyTo(newSGEmail(*message.ReplyTo))
+	}
+
+	for _, attachment := range message.Attachments {
+		email.AddAttachment(mail.NewAttachment().
+			SetContent(base64.StdEncoding.EncodeToString(attachment.Data)).
+			SetType(attachment.ContentType).
+			SetFilename(attachment.Filename).
+			SetDisposition("attachment"))
+	}
+
+	return email
+}
+
+func newSGEmail(addr Address) *mail.Email {
+	return mail.NewEmail(addr.Name, addr.Email)
+}
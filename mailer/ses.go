@@ -0,0 +1,191 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SES sends raw MIME messages through the Amazon SES v2 API.
+type SES struct {
+	Client http.Client
+	// BaseURL defaults to the regional endpoint "https://email.<region>.amazonaws.com".
+	BaseURL         string
+	Region          string
+	AccessKeyId     string
+	SecretAccessKey string
+}
+
+type sesAddresses struct {
+	ToAddresses  []string `json:"ToAddresses"`
+	CcAddresses  []string `json:"CcAddresses,omitempty"`
+	BccAddresses []string `json:"BccAddresses,omitempty"`
+}
+
+type sesRawContent struct {
+	Raw struct {
+		Data []byte `json:"Data"`
+	} `json:"Raw"`
+}
+
+type sesEmail struct {
+	FromEmailAddress string        `json:"FromEmailAddress"`
+	Destination      sesAddresses  `json:"Destination"`
+	ReplyToAddresses []string      `json:"ReplyToAddresses,omitempty"`
+	Content          sesRawContent `json:"Content"`
+}
+
+func (m *SES) Send(message *Message) error {
+	data, err := BuildMIME(message)
+	if err != nil {
+		return err
+	}
+
+	email := sesEmail{
+		FromEmailAddress: message.From.String(),
+	}
+	for _, to := range message.To {
+		email.Destination.ToAddresses = append(email.Destination.ToAddresses, to.String())
+	}
+	for _, cc := range message.Cc {
+		email.Destination.CcAddresses = append(email.Destination.CcAddresses, cc.String())
+	}
+	// Bcc recipients aren't in the raw message headers, so they have to be listed here.
+	for _, bcc := range message.Bcc {
+		email.Destination.BccAddresses = append(email.Destination.BccAddresses, bcc.String())
+	}
+	if message.ReplyTo != nil {
+		email.ReplyToAddresses = []string{message.ReplyTo.String()}
+	}
+	// Data is a byte slice, so it is base64 encoded as the API expects.
+	email.Content.Raw.Data = data
+
+	body, err := json.Marshal(email)
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimSuffix(m.baseURL(), "/") + "/v2/email/outbound-emails"
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	m.sign(req, body, time.Now().UTC())
+
+	return doRequest(&m.Client, req)
+}
+
+func (m *SES) baseURL() string {
+	if m.BaseURL == "" {
+		return fmt.Sprintf("https://email.%s.amazonaws.com", m.Region)
+	}
+	return m.BaseURL
+}
+
+// sign adds an AWS Signature Version 4 authorization header to the request.
+func (m *SES) sign(req *http.Request, body []byte, now time.Time) {
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	signV4(req, payloadHash, now, sigV4Scope{
+		Region:          m.Region,
+		Service:         "ses",
+		AccessKeyId:     m.AccessKeyId,
+		SecretAccessKey: m.SecretAccessKey,
+	})
+}
+
+// sigV4Scope holds the credentials and the scope of a signature.
+type sigV4Scope struct {
+	Region          string
+	Service         string
+	AccessKeyId     string
+	SecretAccessKey string
+}
+
+// signV4 signs the host and every header already set on the request, together with the X-Amz-Date header it adds.
+func signV4(req *http.Request, payloadHash string, now time.Time, scope sigV4Scope) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := now.UTC().Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method, path, canonicalQuery(req.URL.Query()), canonicalHeaders.String(), signedHeaders, payloadHash,
+	}, "\n")
+
+	credentialScope := fmt.Sprintf("%s/%s/%s/aws4_request", date, scope.Region, scope.Service)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzDate, credentialScope, sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signature := hex.EncodeToString(hmacSHA256(signingKey(scope, date), stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		scope.AccessKeyId, credentialScope, signedHeaders, signature,
+	))
+}
+
+func signingKey(scope sigV4Scope, date string) []byte {
+	key := hmacSHA256([]byte("AWS4"+scope.SecretAccessKey), date)
+	key = hmacSHA256(key, scope.Region)
+	key = hmacSHA256(key, scope.Service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+// canonicalQuery sorts the query parameters and escapes them as SigV4 expects, with spaces as %20.
+func canonicalQuery(query url.Values) string {
+	var params []string
+	for name, values := range query {
+		for _, value := range values {
+			params = append(params, sigV4Escape(name)+"="+sigV4Escape(value))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+func sigV4Escape(value string) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,212 @@
+package mailer
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Credentials and time of the AWS Signature Version 4 test suite.
+var awsTestScope = sigV4Scope{
+	Region:          "us-east-1",
+	Service:         "service",
+	AccessKeyId:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+}
+
+var awsTestTime = time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+func TestSigningKey(t *testing.T) {
+	scope := awsTestScope
+	scope.Service = "iam"
+	got := hex.EncodeToString(signingKey(scope, "20150830"))
+	want := "c4afb1cc5771d871763a393e44b703571b55cc28424d1a5e86da6ed3c154a4b9"
+	if got != want {
+		t.Errorf("signingKey() = %s, want %s", got, want)
+	}
+}
+
+func TestSignV4(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		url       string
+		service   string
+		headers   map[string]string
+		signed    string
+		signature string
+	}{
+		{
+			name:      "get-vanilla",
+			method:    http.MethodGet,
+			url:       "https://example.amazonaws.com/",
+			signed:    "host;x-amz-date",
+			signature: "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:      "get-vanilla-query-order-key-case",
+			method:    http.MethodGet,
+			url:       "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			signed:    "host;x-amz-date",
+			signature: "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+		{
+			name:      "post-vanilla",
+			method:    http.MethodPost,
+			url:       "https://example.amazonaws.com/",
+			signed:    "host;x-amz-date",
+			signature: "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+		{
+			name:      "iam-list-users",
+			method:    http.MethodGet,
+			url:       "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08",
+			service:   "iam",
+			headers:   map[string]string{"Content-Type": "application/x-www-form-urlencoded; charset=utf-8"},
+			signed:    "content-type;host;x-amz-date",
+			signature: "5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			scope := awsTestScope
+			if tt.service != "" {
+				scope.Service = tt.service
+			}
+
+			signV4(req, sha256Hex(nil), awsTestTime, scope)
+
+			want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/" + scope.Service + "/aws4_request, " +
+				"SignedHeaders=" + tt.signed + ", Signature=" + tt.signature
+			if got := req.Header.Get("Authorization"); got != want {
+				t.Errorf("Authorization = %s\nwant %s", got, want)
+			}
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("X-Amz-Date = %s", got)
+			}
+		})
+	}
+}
+
+func TestSESSend(t *testing.T) {
+	var got sesEmail
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v2/email/outbound-emails" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		authorization = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("X-Amz-Content-Sha256") != sha256Hex(body) {
+			t.Error("X-Amz-Content-Sha256 doesn't match the body")
+		}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer server.Close()
+
+	m := &SES{BaseURL: server.URL, Region: "eu-west-1", AccessKeyId: "AKID", SecretAccessKey: "secret"}
+	if err := m.Send(testMessage()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKID/") ||
+		!strings.Contains(authorization, "/eu-west-1/ses/aws4_request, ") ||
+		!strings.Contains(authorization, "SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date,") {
+		t.Errorf("unexpected Authorization %s", authorization)
+	}
+	if got.FromEmailAddress != `"My Website" <noreply@example.com>` {
+		t.Errorf("FromEmailAddress = %s", got.FromEmailAddress)
+	}
+	if len(got.Destination.ToAddresses) != 1 || len(got.Destination.CcAddresses) != 1 || len(got.Destination.BccAddresses) != 1 {
+		t.Errorf("unexpected Destination %+v", got.Destination)
+	}
+	if len(got.ReplyToAddresses) != 1 || got.ReplyToAddresses[0] != `"Ann Lee" <ann@example.com>` {
+		t.Errorf("ReplyToAddresses = %v", got.ReplyToAddresses)
+	}
+	raw := string(got.Content.Raw.Data)
+	if !strings.Contains(raw, "Subject: Hello") || strings.Contains(raw, "bcc@example.com") {
+		t.Errorf("unexpected raw message:\n%s", raw)
+	}
+}
+
+func TestSESSendErrors(t *testing.T) {
+	testStatusErrors(t, func(url string) Mailer {
+		return &SES{BaseURL: url, Region: "eu-west-1", AccessKeyId: "AKID", SecretAccessKey: "secret"}
+	})
+}
+
+func testMessage() *Message {
+	replyTo := NewAddress("Ann Lee", "ann@example.com")
+	return &Message{
+		From:    NewAddress("My Website", "noreply@example.com"),
+		To:      []Address{NewAddress("Bob Stone", "bob@example.com")},
+		Cc:      []Address{NewAddress("", "cc@example.com")},
+		Bcc:     []Address{NewAddress("", "bcc@example.com")},
+		ReplyTo: &replyTo,
+		Subject: "Hello",
+		Text:    "Hello, Bob",
+		HTML:    "<p>Hello, Bob</p>",
+		Attachments: []Attachment{
+			{Filename: "notes.txt", ContentType: "text/plain", Data: []byte("notes")},
+		},
+	}
+}
+
+// testStatusErrors checks that error responses of the provider API are permanent or transient as expected.
+func testStatusErrors(t *testing.T, newMailer func(url string) Mailer) {
+	tests := []struct {
+		status    int
+		transient bool
+	}{
+		{http.StatusUnauthorized, false},
+		{http.StatusUnprocessableEntity, false},
+		{http.StatusTooManyRequests, true},
+		{http.StatusServiceUnavailable, true},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "rejected", tt.status)
+			}))
+			defer server.Close()
+
+			err := newMailer(server.URL).Send(testMessage())
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.status {
+				t.Fatalf("Send() error = %v, want status %d", err, tt.status)
+			}
+			if !strings.Contains(statusErr.Body, "rejected") {
+				t.Errorf("Body = %q", statusErr.Body)
+			}
+			if IsTransient(err) != tt.transient {
+				t.Errorf("IsTransient() = %v, want %v", !tt.transient, tt.transient)
+			}
+		})
+	}
+
+	t.Run("unreachable", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		url := server.URL
+		server.Close()
+
+		err := newMailer(url).Send(testMessage())
+		if err == nil || !IsTransient(err) {
+			t.Errorf("Send() error = %v, want a transient error", err)
+		}
+	})
+}
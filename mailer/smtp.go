@@ -0,0 +1,120 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+type TLSMode string
+
+const (
+	// TLSNone sends everything in plain text, only use it for local development.
+	TLSNone TLSMode = "none"
+	// TLSStartTLS upgrades a plain connection, usually on port 587.
+	TLSStartTLS TLSMode = "starttls"
+	// TLSImplicit connects over TLS from the start, usually on port 465.
+	TLSImplicit TLSMode = "tls"
+)
+
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	TLSMode  TLSMode
+	Timeout  time.Duration
+}
+
+func (m *SMTP) Send(message *Message) error {
+	data, err := BuildMIME(message)
+	if err != nil {
+		return err
+	}
+
+	client, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err = client.Mail(message.From.Email); err != nil {
+		return err
+	}
+	for _, rcpt := range message.Recipients() {
+		if err = client.Rcpt(rcpt.Email); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = writer.Write(data); err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+func (m *SMTP) connect() (*smtp.Client, error) {
+	addr := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	dialer := &net.Dialer{Timeout: m.Timeout}
+
+	var conn net.Conn
+	var err error
+	if m.TLSMode == TLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: m.Host})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.Timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(m.Timeout))
+	}
+
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err = m.handshake(client); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+func (m *SMTP) handshake(client *smtp.Client) error {
+	if m.TLSMode == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("server does not support STARTTLS")
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: m.Host}); err != nil {
+			return err
+		}
+	}
+
+	if m.Username == "" {
+		return nil
+	}
+	if ok, _ := client.Extension("AUTH"); !ok {
+		return errors.New("server does not support AUTH")
+	}
+	// PlainAuth refuses to send credentials over unencrypted connections, except to localhost.
+	if err := client.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package mailer
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSMTPServer is a local stand-in for an SMTP server. It accepts everything,
+// except the commands starting with a prefix listed in replies, which get the given reply instead.
+type fakeSMTPServer struct {
+	listener net.Listener
+	replies  map[string]string
+	auth     bool
+
+	mu       sync.Mutex
+	commands []string
+	data     string
+}
+
+// The server advertises AUTH when auth is set.
+func newFakeSMTPServer(t *testing.T, replies map[string]string, auth bool) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeSMTPServer{listener: listener, replies: replies, auth: auth}
+	t.Cleanup(func() { listener.Close() })
+	go server.serve()
+	return server
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	_ = text.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, line)
+		s.mu.Unlock()
+
+		if reply, ok := s.reply(line); ok {
+			_ = text.PrintfLine("%s", reply)
+			continue
+		}
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO":
+			if s.auth {
+				_ = text.PrintfLine("250-localhost")
+				_ = text.PrintfLine("250 AUTH PLAIN")
+			} else {
+				_ = text.PrintfLine("250 localhost")
+			}
+		case "DATA":
+			_ = text.PrintfLine("354 go ahead")
+			data, _ := text.ReadDotBytes()
+			s.mu.Lock()
+			s.data = string(data)
+			s.mu.Unlock()
+			_ = text.PrintfLine("250 queued")
+		case "AUTH":
+			_ = text.PrintfLine("235 authenticated")
+		case "QUIT":
+			_ = text.PrintfLine("221 bye")
+			return
+		default:
+			_ = text.PrintfLine("250 ok")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) reply(line string) (string, bool) {
+	for prefix, reply := range s.replies {
+		if strings.HasPrefix(line, prefix) {
+			return reply, true
+		}
+	}
+	return "", false
+}
+
+func (s *fakeSMTPServer) received() ([]string, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...), s.data
+}
+
+func TestSMTPSend(t *testing.T) {
+	server := newFakeSMTPServer(t, nil, false)
+	m := &SMTP{Host: "127.0.0.1", Port: server.port(), TLSMode: TLSNone, Timeout: time.Second}
+	if err := m.Send(testMessage()); err != nil {
+		t.Fatal(err)
+	}
+
+	commands, data := server.received()
+	want := []string{
+		"MAIL FROM:<noreply@example.com>",
+		"RCPT TO:<bob@example.com>",
+		"RCPT TO:<cc@example.com>",
+		"RCPT TO:<bcc@example.com>",
+		"DATA",
+		"QUIT",
+	}
+	if got := commands[1:]; strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands = %q\nwant %q", got, want)
+	}
+
+	message, err := textproto.NewReader(bufio.NewReader(strings.NewReader(data))).ReadMIMEHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Get("Subject") != "Hello" || message.Get("Reply-To") != `"Ann Lee" <ann@example.com>` {
+		t.Errorf("unexpected headers %v", message)
+	}
+	if message.Get("Bcc") != "" || strings.Contains(data, "bcc@example.com") {
+		t.Error("Bcc recipients should not be part of the message")
+	}
+}
+
+func TestSMTPSendErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		replies   map[string]string
+		mailer    SMTP
+		code      int
+		transient bool
+		errText   string
+	}{
+		{
+			name:    "rejected recipient",
+			replies: map[string]string{"RCPT TO:<cc@": "550 no such user"},
+			code:    550,
+		},
+		{
+			name:      "greylisted",
+			replies:   map[string]string{"MAIL FROM": "451 try again later"},
+			code:      451,
+			transient: true,
+		},
+		{
+			name:    "authentication failed",
+			replies: map[string]string{"AUTH": "535 bad credentials"},
+			mailer:  SMTP{Username: "user", Password: "secret"},
+			code:    535,
+		},
+		{
+			name:    "missing STARTTLS",
+			mailer:  SMTP{TLSMode: TLSStartTLS},
+			errText: "does not support STARTTLS",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeSMTPServer(t, tt.replies, tt.mailer.Username != "")
+			m := tt.mailer
+			m.Host, m.Port, m.Timeout = "127.0.0.1", server.port(), time.Second
+			if m.TLSMode == "" {
+				m.TLSMode = TLSNone
+			}
+
+			err := m.Send(testMessage())
+			if err == nil {
+				t.Fatal("Send() should fail")
+			}
+			if tt.code != 0 && !strings.Contains(err.Error(), strconv.Itoa(tt.code)) {
+				t.Errorf("Send() error = %v, want code %d", err, tt.code)
+			}
+			if tt.errText != "" {
+				if !strings.Contains(err.Error(), tt.errText) {
+					t.Errorf("Send() error = %v, want %q", err, tt.errText)
+				}
+				return
+			}
+			if IsTransient(err) != tt.transient {
+				t.Errorf("IsTransient(%v) = %v, want %v", err, !tt.transient, tt.transient)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package sail
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/demianbucik/sail/config"
+	"github.com/demianbucik/sail/mailer"
+)
+
+const emailTimeout = 5 * time.Second
+
+// NewMailer creates the mailer for the configured chain of email providers.
+func NewMailer(env *config.Environ) (mailer.Mailer, error) {
+	providers := env.Providers()
+	if len(providers) == 1 {
+		m, err := newProviderMailer(env, providers[0])
+		if err != nil {
+			return nil, err
+		}
+		return mailer.WithRetry(m, retries, retryBackOff), nil
+	}
+
+	failover := &mailer.Failover{
+		Threshold: env.FailureThreshold(),
+		CoolDown:  env.CoolDown(),
+	}
+	// The health of the whole chain shows whether the remaining providers can take over.
+	failover.OnOpen = func(provider mailer.Provider, err error) {
+		log.WithError(err).WithFields(log.Fields{
+			"provider":  provider,
+			"providers": failover.Health(),
+		}).Warn("Email provider circuit breaker opened")
+	}
+	for _, provider := range providers {
+		m, err := newProviderMailer(env, provider)
+		if err != nil {
+			return nil, err
+		}
+		failover.Add(provider, mailer.WithRetry(m, retries, retryBackOff))
+	}
+	return failover, nil
+}
+
+func newProviderMailer(env *config.Environ, provider mailer.Provider) (mailer.Mailer, error) {
+	switch provider {
+	case mailer.SendGridProvider:
+		sendGrid := mailer.NewSendGrid(env.SendGridApiKey)
+		sendGrid.SandboxMode = bool(env.SendGridSandboxMode)
+		return sendGrid, nil
+	case mailer.SMTPProvider:
+		return &mailer.SMTP{
+			Host:     env.SMTPHost,
+			Port:     env.Port(),
+			Username: env.SMTPUsername,
+			Password: env.SMTPPassword,
+			TLSMode:  env.TLSMode(),
+			Timeout:  emailTimeout,
+		}, nil
+	case mailer.MailgunProvider:
+		return &mailer.Mailgun{
+			Client:  http.Client{Timeout: emailTimeout},
+			BaseURL: env.MailgunBaseURL,
+			Domain:  env.MailgunDomain,
+			ApiKey:  env.MailgunApiKey,
+		}, nil
+	case mailer.PostmarkProvider:
+		return &mailer.Postmark{
+			Client:        http.Client{Timeout: emailTimeout},
+			BaseURL:       env.PostmarkBaseURL,
+			ServerToken:   env.PostmarkServerToken,
+			MessageStream: env.PostmarkMessageStream,
+		}, nil
+	case mailer.SESProvider:
+		return &mailer.SES{
+			Client:          http.Client{Timeout: emailTimeout},
+			BaseURL:         env.SESBaseURL,
+			Region:          env.SESRegion,
+			AccessKeyId:     env.SESAccessKeyId,
+			SecretAccessKey: env.SESSecretAccessKey,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported email provider '%s'", provider)
+	}
+}
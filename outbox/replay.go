@@ -0,0 +1,27 @@
+package outbox
+
+import (
+	"time"
+
+	"github.com/demianbucik/sail/mailer"
+)
+
+// Replay sends a stored entry again and removes it from the store once delivered.
+// A failed attempt is added to the entry's error history.
+func Replay(store Store, m mailer.Mailer, id string) error {
+	entry, err := store.Get(id)
+	if err != nil {
+		return err
+	}
+
+	entry.Attempts++
+	if err = m.Send(entry.Message); err != nil {
+		entry.Errors = append(entry.Errors, AttemptError{Time: time.Now(), Error: err.Error()})
+		if putErr := store.Put(entry); putErr != nil {
+			return putErr
+		}
+		return err
+	}
+
+	return store.Delete(id)
+}
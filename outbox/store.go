@@ -0,0 +1,162 @@
+//go:generate mockery --inpackage --name=Store
+
+package outbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/demianbucik/sail/mailer"
+)
+
+var ErrNotFound = errors.New("entry not found")
+
+type Entry struct {
+	ID           string          `json:"id"`
+	SubmissionID string          `json:"submissionId"`
+	Message      *mailer.Message `json:"message"`
+	CreatedAt    time.Time       `json:"createdAt"`
+	Attempts     int             `json:"attempts"`
+	NextAttempt  time.Time       `json:"nextAttempt"`
+	Errors       []AttemptError  `json:"errors,omitempty"`
+	// DeliveredAt is set when the entry was sent, but couldn't be removed from the store.
+	// Delivered entries are only removed, never sent again.
+	DeliveredAt time.Time `json:"deliveredAt,omitempty"`
+}
+
+type AttemptError struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+}
+
+type Store interface {
+	Put(entry *Entry) error
+	Get(id string) (*Entry, error)
+	Delete(id string) error
+	List() ([]*Entry, error)
+}
+
+// FileStore keeps every entry in its own JSON file inside a directory.
+// Writes go through a temporary file and a rename, so entries survive crashes and restarts intact.
+type FileStore struct {
+	Dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{Dir: dir}, nil
+}
+
+func (s *FileStore) Put(entry *Entry) error {
+	if !validID(entry.ID) {
+		return fmt.Errorf("invalid entry id '%s'", entry.ID)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.Dir, entry.ID+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(entry.ID))
+}
+
+func (s *FileStore) Get(id string) (*Entry, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry := &Entry{}
+	if err = json.Unmarshal(data, entry); err != nil {
+		return nil, fmt.Errorf("decoding entry '%s' failed: %w", id, err)
+	}
+	return entry, nil
+}
+
+func (s *FileStore) Delete(id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+	err := os.Remove(s.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// List returns all entries, oldest first.
+func (s *FileStore) List() ([]*Entry, error) {
+	files, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, file := range files {
+		id, ok := strings.CutSuffix(file.Name(), ".json")
+		if file.IsDir() || !ok {
+			continue
+		}
+		entry, err := s.Get(id)
+		if errors.Is(err, ErrNotFound) {
+			// Deleted in the meantime.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.Dir, id+".json")
+}
+
+// validID prevents ids from escaping the store directory.
+func validID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
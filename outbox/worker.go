@@ -0,0 +1,219 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/demianbucik/sail/mailer"
+)
+
+// minWait keeps the worker from spinning when the earliest retry is already due but the entry can't be processed.
+const minWait = time.Second
+
+// Worker delivers stored entries in the background, transient failures are retried with an exponential back-off.
+type Worker struct {
+	Store  Store
+	Mailer mailer.Mailer
+	// Optional, entries are moved here after MaxAttempts failed deliveries, or right away when they fail permanently.
+	// Without it, transient failures are retried indefinitely and permanent failures are dropped.
+	DeadLetters Store
+	MaxAttempts int
+
+	PollInterval time.Duration
+	BackOff      time.Duration
+	MaxBackOff   time.Duration
+
+	wake chan struct{}
+	// IDs of the entries that were sent, but couldn't be marked as delivered in the store either.
+	delivered map[string]bool
+}
+
+func NewWorker(store Store, m mailer.Mailer) *Worker {
+	return &Worker{
+		Store:        store,
+		Mailer:       m,
+		PollInterval: 30 * time.Second,
+		BackOff:      30 * time.Second,
+		MaxBackOff:   time.Hour,
+		wake:         make(chan struct{}, 1),
+		delivered:    make(map[string]bool),
+	}
+}
+
+// Enqueue persists the message and wakes up the worker.
+func (w *Worker) Enqueue(id, submissionId string, message *mailer.Message) error {
+	now := time.Now()
+	err := w.Store.Put(&Entry{
+		ID:           id,
+		SubmissionID: submissionId,
+		Message:      message,
+		CreatedAt:    now,
+		NextAttempt:  now,
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run processes due entries until the context is cancelled.
+// Entries left over from a previous run are picked up on start.
+func (w *Worker) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-w.wake:
+			if !timer.Stop() {
+				<-timer.C
+			}
+		}
+
+		timer.Reset(w.wait(w.processDue()))
+	}
+}
+
+// wait returns how long to wait for the next run, given the time of the earliest pending retry.
+func (w *Worker) wait(next time.Time) time.Duration {
+	wait := w.PollInterval
+	if !next.IsZero() && time.Until(next) < wait {
+		wait = time.Until(next)
+	}
+	if wait < minWait {
+		wait = minWait
+	}
+	return wait
+}
+
+// processDue delivers due entries and returns the time of the earliest pending retry.
+func (w *Worker) processDue() time.Time {
+	entries, err := w.Store.List()
+	if err != nil {
+		log.WithError(err).Error("Listing outbox entries failed")
+		return time.Time{}
+	}
+
+	var next time.Time
+	for _, entry := range entries {
+		if w.isDelivered(entry) {
+			w.removeDelivered(entry)
+			continue
+		}
+		if entry.NextAttempt.After(time.Now()) || !w.deliver(entry) {
+			if next.IsZero() || entry.NextAttempt.Before(next) {
+				next = entry.NextAttempt
+			}
+		}
+	}
+	return next
+}
+
+// deliver sends a single entry and reports whether it's done with it, so it doesn't have to be retried.
+func (w *Worker) deliver(entry *Entry) bool {
+	logEntry := log.WithField("outboxEntry", entry.ID).WithField("submissionId", entry.SubmissionID)
+
+	entry.Attempts++
+	err := w.Mailer.Send(entry.Message)
+	if err == nil {
+		logEntry.WithField("attempts", entry.Attempts).Info("Email sent successfully")
+		entry.DeliveredAt = time.Now()
+		w.removeDelivered(entry)
+		// The entry won't be sent again, even when it couldn't be removed yet.
+		return true
+	}
+
+	entry.Errors = append(entry.Errors, AttemptError{Time: time.Now(), Error: err.Error()})
+	entry.NextAttempt = time.Now().Add(w.backOff(entry.Attempts))
+	logEntry = logEntry.WithError(err).WithField("attempts", entry.Attempts)
+
+	if !mailer.IsTransient(err) {
+		if w.DeadLetters != nil {
+			return w.moveToDeadLetters(entry, logEntry)
+		}
+		return w.drop(entry, logEntry)
+	}
+	if w.DeadLetters != nil && entry.Attempts >= w.MaxAttempts {
+		return w.moveToDeadLetters(entry, logEntry)
+	}
+
+	if err = w.Store.Put(entry); err != nil {
+		logEntry.WithField("storeError", err.Error()).Error("Updating outbox entry failed")
+		return false
+	}
+	logEntry.WithField("nextAttempt", entry.NextAttempt).Warn("Sending email failed, will retry")
+	return false
+}
+
+func (w *Worker) moveToDeadLetters(entry *Entry, logEntry *log.Entry) bool {
+	if err := w.DeadLetters.Put(entry); err != nil {
+		logEntry.WithField("storeError", err.Error()).Error("Storing dead letter failed")
+		return false
+	}
+	if err := w.Store.Delete(entry.ID); err != nil {
+		logEntry.WithField("storeError", err.Error()).Error("Deleting dead outbox entry failed")
+		return false
+	}
+	logEntry.Error("Sending email failed, moved to dead letters")
+	return true
+}
+
+// drop removes an entry that failed permanently when there is no dead letter store to keep it.
+func (w *Worker) drop(entry *Entry, logEntry *log.Entry) bool {
+	if err := w.Store.Delete(entry.ID); err != nil {
+		logEntry.WithField("storeError", err.Error()).Error("Deleting failed outbox entry failed")
+		return false
+	}
+	logEntry.Error("Sending email failed permanently, dropped")
+	return true
+}
+
+func (w *Worker) isDelivered(entry *Entry) bool {
+	return !entry.DeliveredAt.IsZero() || w.delivered[entry.ID]
+}
+
+// removeDelivered deletes a delivered entry from the store. When that fails, the entry is marked as delivered,
+// in the store if possible and in memory otherwise, and the deletion is retried on the next run.
+func (w *Worker) removeDelivered(entry *Entry) {
+	logEntry := log.WithField("outboxEntry", entry.ID).WithField("submissionId", entry.SubmissionID)
+
+	err := w.Store.Delete(entry.ID)
+	if err == nil || errors.Is(err, ErrNotFound) {
+		delete(w.delivered, entry.ID)
+		return
+	}
+	logEntry.WithError(err).Error("Deleting delivered outbox entry failed")
+
+	if entry.DeliveredAt.IsZero() {
+		entry.DeliveredAt = time.Now()
+	}
+	if err = w.Store.Put(entry); err != nil {
+		logEntry.WithField("storeError", err.Error()).Error("Marking outbox entry as delivered failed")
+		if w.delivered == nil {
+			w.delivered = make(map[string]bool)
+		}
+		w.delivered[entry.ID] = true
+	}
+}
+
+func (w *Worker) backOff(attempts int) time.Duration {
+	backOff := w.BackOff
+	for i := 1; i < attempts && backOff < w.MaxBackOff; i++ {
+		backOff *= 2
+	}
+	if backOff > w.MaxBackOff {
+		return w.MaxBackOff
+	}
+	return backOff
+}
@@ -0,0 +1,200 @@
+package outbox
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/demianbucik/sail/mailer"
+)
+
+// memoryStore keeps the entries in a map, deleteErr and putErr make the operations fail.
+type memoryStore struct {
+	entries   map[string]Entry
+	deleteErr error
+	putErr    error
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{entries: make(map[string]Entry)}
+}
+
+func (s *memoryStore) Put(entry *Entry) error {
+	if s.putErr != nil {
+		return s.putErr
+	}
+	s.entries[entry.ID] = *entry
+	return nil
+}
+
+func (s *memoryStore) Get(id string) (*Entry, error) {
+	entry, ok := s.entries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &entry, nil
+}
+
+func (s *memoryStore) Delete(id string) error {
+	if s.deleteErr != nil {
+		return s.deleteErr
+	}
+	if _, ok := s.entries[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.entries, id)
+	return nil
+}
+
+func (s *memoryStore) List() ([]*Entry, error) {
+	var entries []*Entry
+	for _, entry := range s.entries {
+		entry := entry
+		entries = append(entries, &entry)
+	}
+	return entries, nil
+}
+
+// stubMailer fails every call with err and counts the calls.
+type stubMailer struct {
+	err   error
+	calls int
+}
+
+func (m *stubMailer) Send(*mailer.Message) error {
+	m.calls++
+	return m.err
+}
+
+var (
+	errTransient = &mailer.StatusError{StatusCode: http.StatusServiceUnavailable}
+	errPermanent = &mailer.StatusError{StatusCode: http.StatusUnprocessableEntity}
+)
+
+func newTestWorker(store Store, m mailer.Mailer) *Worker {
+	w := NewWorker(store, m)
+	if err := w.Enqueue("s1-email", "s1", &mailer.Message{Subject: "Hello"}); err != nil {
+		panic(err)
+	}
+	return w
+}
+
+func TestWorkerFailures(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		deadLetters    bool
+		wantCalls      int
+		wantOutbox     bool
+		wantDeadLetter bool
+	}{
+		{"permanent to dead letters", errPermanent, true, 1, false, true},
+		{"permanent dropped", errPermanent, false, 1, false, false},
+		{"transient retried", errTransient, false, 1, true, false},
+		{"transient below max attempts", errTransient, true, 1, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, m := newMemoryStore(), &stubMailer{err: tt.err}
+			w := newTestWorker(store, m)
+			deadLetters := newMemoryStore()
+			if tt.deadLetters {
+				w.DeadLetters, w.MaxAttempts = deadLetters, 3
+			}
+
+			// The second run is before the back-off elapses, so only permanent failures could be sent again.
+			w.processDue()
+			w.processDue()
+
+			if m.calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", m.calls, tt.wantCalls)
+			}
+			if _, ok := store.entries["s1-email"]; ok != tt.wantOutbox {
+				t.Errorf("entry in outbox = %v, want %v", ok, tt.wantOutbox)
+			}
+			if _, ok := deadLetters.entries["s1-email"]; ok != tt.wantDeadLetter {
+				t.Errorf("entry in dead letters = %v, want %v", ok, tt.wantDeadLetter)
+			}
+		})
+	}
+}
+
+func TestWorkerMaxAttempts(t *testing.T) {
+	store, m := newMemoryStore(), &stubMailer{err: errTransient}
+	w := newTestWorker(store, m)
+	deadLetters := newMemoryStore()
+	w.DeadLetters, w.MaxAttempts, w.BackOff = deadLetters, 2, 0
+
+	w.processDue()
+	w.processDue()
+	if m.calls != 2 || len(store.entries) != 0 {
+		t.Fatalf("calls = %d, outbox entries = %d", m.calls, len(store.entries))
+	}
+	entry, ok := deadLetters.entries["s1-email"]
+	if !ok || entry.Attempts != 2 || len(entry.Errors) != 2 {
+		t.Errorf("unexpected dead letter %+v", entry)
+	}
+}
+
+func TestWorkerDeleteFailsAfterSend(t *testing.T) {
+	store, m := newMemoryStore(), &stubMailer{}
+	w := newTestWorker(store, m)
+	store.deleteErr = errors.New("disk full")
+
+	if next := w.processDue(); !next.IsZero() {
+		t.Errorf("delivered entry should not be scheduled, next = %v", next)
+	}
+	w.processDue()
+	if m.calls != 1 {
+		t.Errorf("calls = %d, delivered entry should not be sent again", m.calls)
+	}
+	if entry := store.entries["s1-email"]; entry.DeliveredAt.IsZero() {
+		t.Error("entry should be marked as delivered")
+	}
+
+	store.deleteErr = nil
+	w.processDue()
+	if m.calls != 1 || len(store.entries) != 0 {
+		t.Errorf("calls = %d, outbox entries = %d", m.calls, len(store.entries))
+	}
+}
+
+func TestWorkerDeleteAndPutFailAfterSend(t *testing.T) {
+	store, m := newMemoryStore(), &stubMailer{}
+	w := newTestWorker(store, m)
+	store.deleteErr, store.putErr = errors.New("disk full"), errors.New("disk full")
+
+	w.processDue()
+	w.processDue()
+	if m.calls != 1 {
+		t.Errorf("calls = %d, delivered entry should not be sent again", m.calls)
+	}
+
+	store.deleteErr, store.putErr = nil, nil
+	w.processDue()
+	if len(store.entries) != 0 || len(w.delivered) != 0 {
+		t.Errorf("outbox entries = %d, delivered = %v", len(store.entries), w.delivered)
+	}
+}
+
+func TestWorkerWait(t *testing.T) {
+	w := NewWorker(newMemoryStore(), &stubMailer{})
+	tests := []struct {
+		name string
+		next time.Time
+		want time.Duration
+	}{
+		{"nothing pending", time.Time{}, w.PollInterval},
+		{"retry before the poll", time.Now().Add(10 * time.Second), 10 * time.Second},
+		{"retry already due", time.Now().Add(-time.Minute), minWait},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := w.wait(tt.next)
+			if got > tt.want || got < tt.want-time.Second {
+				t.Errorf("wait() = %v, want about %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package sail
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/demianbucik/sail/config"
+	"github.com/demianbucik/sail/mailer"
+)
+
+// TemplatePreview is a template rendered with sample data, or the reason it couldn't be rendered.
+// Subject and sender name templates are named after their setting and marked as headers.
+type TemplatePreview struct {
+	Name     string
+	Body     string
+	IsHTML   bool
+	IsHeader bool
+	Err      error
+}
+
+// PreviewTemplates renders the templates of the form with sample values, which can be replaced with the
+// values in data. These are the configured templates with their plain-text or HTML counterparts and the
+// localized confirmation templates. Besides the template files, it renders the subject and sender name
+// templates, and reports the configured templates that don't exist. Previews are sorted by name.
+func PreviewTemplates(env *config.Environ, formId string, data map[string]any) ([]TemplatePreview, error) {
+	formConf, ok := env.Form(formId)
+	if !ok {
+		return nil, fmt.Errorf("unknown form '%s'", formId)
+	}
+	embedded, err := fs.Sub(templatesFS, "templates")
+	if err != nil {
+		return nil, err
+	}
+	files, err := readTemplateFiles(templateDirs(embedded, env.TemplatesDir)...)
+	if err != nil {
+		return nil, err
+	}
+
+	sampleData := templateData(formConf, sampleForm(formConf))
+	for key, value := range data {
+		sampleData[key] = value
+	}
+
+	configured := []string{formConf.EmailTemplateFile}
+	if formConf.ConfirmationEnabled() {
+		configured = append(configured, formConf.ConfirmationTemplateFile)
+	}
+
+	// Every file is parsed, because the form's templates can include the others.
+	var previews []TemplatePreview
+	set := newTemplateSet()
+	set.inlineCSS = bool(env.InlineCSS)
+	var parsed []string
+	for name, content := range files {
+		used := usesTemplate(formConf, configured, name)
+		if err = set.parse(name, content); err != nil {
+			if used {
+				previews = append(previews, TemplatePreview{Name: name, IsHTML: isHTMLTemplate(name), Err: err})
+			}
+			continue
+		}
+		if used {
+			parsed = append(parsed, name)
+		}
+	}
+	for _, name := range parsed {
+		body, isHTML, err := set.execute(name, sampleData)
+		previews = append(previews, TemplatePreview{Name: name, Body: string(body), IsHTML: isHTML, Err: err})
+	}
+
+	for _, name := range configured {
+		if _, ok := files[name]; !ok {
+			previews = append(previews, TemplatePreview{Name: name, Err: fmt.Errorf("template '%s' not found", name)})
+		}
+	}
+
+	headers := map[string]string{
+		"EMAIL_SUBJECT":          formConf.EmailSubject,
+		"EMAIL_FROM_NAME":        formConf.EmailFromName,
+		"CONFIRMATION_SUBJECT":   formConf.ConfirmationSubject,
+		"CONFIRMATION_FROM_NAME": formConf.ConfirmationFromName,
+	}
+	for lang, text := range formConf.ConfirmationSubjects {
+		headers["CONFIRMATION_SUBJECTS."+lang] = text
+	}
+	for name, text := range headers {
+		if text == "" {
+			continue
+		}
+		body, err := renderHeader(text, "", sampleData)
+		previews = append(previews, TemplatePreview{Name: name, Body: body, IsHeader: true, Err: err})
+	}
+
+	sort.Slice(previews, func(i, j int) bool {
+		return previews[i].Name < previews[j].Name
+	})
+	return previews, nil
+}
+
+// usesTemplate reports whether the named template is one of the configured templates, their counterpart with
+// a ".txt", ".html" or ".htm" extension, or a localized confirmation template, for example "confirmation.de.html".
+func usesTemplate(formConf *config.Form, configured []string, name string) bool {
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	isCounterpart := ext == ".txt" || isHTMLTemplate(name)
+	for _, file := range configured {
+		base := strings.TrimSuffix(file, path.Ext(file))
+		if name == file || isCounterpart && stem == base {
+			return true
+		}
+		if file != formConf.ConfirmationTemplateFile || !isCounterpart && ext != path.Ext(file) {
+			continue
+		}
+		if lang, ok := strings.CutPrefix(stem, base+"."); ok && lang != "" && !strings.Contains(lang, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// sampleForm returns a submission with a sample value for every declared field.
+func sampleForm(formConf *config.Form) *EmailForm {
+	now := time.Now()
+	form := &EmailForm{
+		Fields:       make(map[string]string),
+		AllFields:    make(map[string]string),
+		SubmissionId: "0123456789abcdef01234567",
+		SubmittedAt:  now,
+		ClientIp:     "203.0.113.7",
+		UserAgent:    "Mozilla/5.0 (Sail preview)",
+		Referer:      "https://example.com/contact",
+	}
+	for _, field := range formConf.Fields() {
+		var value string
+		switch {
+		case field.Name == "name":
+			value = "Jane Doe"
+		case field.Name == "message":
+			value = "Hello!\n\nThis is a sample message,\nit spans multiple lines."
+		case field.Type == config.FieldEmail:
+			value = "jane.doe@example.com"
+		case field.Type == config.FieldNumber:
+			value = "42"
+		case field.Type == config.FieldDate:
+			value = now.Format(dateLayout)
+		case field.Type == config.FieldCheckbox:
+			value = "true"
+		case field.Type == config.FieldSelect && len(field.Options) > 0:
+			value = field.Options[0]
+		default:
+			value = "Sample " + strings.ReplaceAll(field.Name, "-", " ")
+		}
+		form.Fields[field.Name] = value
+		form.AllFields[field.Name] = value
+	}
+	form.Name = form.Fields["name"]
+	form.Email = form.Fields["email"]
+	form.Subject = form.Fields["subject"]
+	form.Message = form.Fields["message"]
+
+	if formConf.AssignmentEnabled() {
+		assignee := formConf.Assignees[0]
+		address := mailer.NewAddress(assignee.Name, assignee.Email)
+		form.Assignee = &address
+	}
+	return form
+}
@@ -0,0 +1,165 @@
+//go:generate mockery --inpackage --name=Store
+
+// Package ratelimit limits how often a key, such as a client IP, may submit a form, using token buckets.
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit allows Count submissions per Period. Unused submissions accumulate up to Count,
+// so a client can submit Count times in a row, then once every Period/Count.
+// The zero Limit is disabled.
+type Limit struct {
+	Count  int
+	Period time.Duration
+}
+
+// ParseLimit parses limits written as "<count>/<period>", for example "5/1m" or "100/24h".
+// The period defaults to one unit, so "10/h" is the same as "10/1h".
+func ParseLimit(text string) (Limit, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Limit{}, nil
+	}
+	count, period, ok := strings.Cut(text, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid limit '%s', use '<count>/<period>' such as '5/1m'", text)
+	}
+	limit := Limit{}
+	var err error
+	if limit.Count, err = strconv.Atoi(strings.TrimSpace(count)); err != nil || limit.Count <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit '%s', the count should be a positive number", text)
+	}
+	period = strings.TrimSpace(period)
+	if period != "" && (period[0] < '0' || period[0] > '9') {
+		period = "1" + period
+	}
+	if limit.Period, err = time.ParseDuration(period); err != nil || limit.Period <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit '%s', the period should be a positive duration", text)
+	}
+	return limit, nil
+}
+
+func (limit Limit) Enabled() bool {
+	return limit.Count > 0
+}
+
+func (limit Limit) String() string {
+	if !limit.Enabled() {
+		return ""
+	}
+	return fmt.Sprintf("%d/%s", limit.Count, limit.Period)
+}
+
+func (limit Limit) MarshalText() ([]byte, error) {
+	return []byte(limit.String()), nil
+}
+
+func (limit *Limit) UnmarshalText(text []byte) error {
+	parsed, err := ParseLimit(string(text))
+	if err != nil {
+		return err
+	}
+	*limit = parsed
+	return nil
+}
+
+// rate returns the number of tokens added to a bucket per second.
+func (limit Limit) rate() float64 {
+	return float64(limit.Count) / limit.Period.Seconds()
+}
+
+// Store keeps the token buckets. A store shared by several instances, for example in Redis,
+// applies the limits across all of them, it has to take tokens atomically.
+type Store interface {
+	// Take removes a token from the bucket of the key. When the bucket is empty, it reports
+	// how long it takes until the next token is added instead.
+	Take(key string, limit Limit) (allowed bool, retryAfter time.Duration, err error)
+	// Peek reports what Take would, without removing a token.
+	Peek(key string, limit Limit) (allowed bool, retryAfter time.Duration, err error)
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// refill adds the tokens accumulated since the last update.
+func (b *bucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.updated).Seconds() * b.limit.rate()
+	if capacity := float64(b.limit.Count); b.tokens > capacity {
+		b.tokens = capacity
+	}
+	b.updated = now
+}
+
+// MemoryStore keeps the buckets in memory, so every instance has its own limits.
+// Full buckets are removed periodically, as they're the same as new ones.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	// now returns the current time, tests replace it.
+	now func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), lastSweep: time.Now()}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit) (bool, time.Duration, error) {
+	return s.use(key, limit, true)
+}
+
+func (s *MemoryStore) Peek(key string, limit Limit) (bool, time.Duration, error) {
+	return s.use(key, limit, false)
+}
+
+// use checks the bucket of the key, and removes a token when take is set and the bucket isn't empty.
+func (s *MemoryStore) use(key string, limit Limit, take bool) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock()
+	if now.Sub(s.lastSweep) > time.Minute {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: float64(limit.Count), updated: now, limit: limit}
+		s.buckets[key] = b
+	}
+	b.refill(now)
+	if b.tokens >= 1 {
+		if take {
+			b.tokens--
+		}
+		return true, 0, nil
+	}
+	retryAfter := time.Duration((1 - b.tokens) / limit.rate() * float64(time.Second))
+	return false, retryAfter, nil
+}
+
+func (s *MemoryStore) clock() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Count) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestStore() (*MemoryStore, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	store.now = clock.Now
+	store.lastSweep = clock.now
+	return store, clock
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    Limit
+		wantErr bool
+	}{
+		{"", Limit{}, false},
+		{"5/1m", Limit{5, time.Minute}, false},
+		{" 10 / h ", Limit{10, time.Hour}, false},
+		{"100/24h", Limit{100, 24 * time.Hour}, false},
+		{"5", Limit{}, true},
+		{"0/1m", Limit{}, true},
+		{"5/0s", Limit{}, true},
+		{"5/soon", Limit{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := ParseLimit(tt.text)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseLimit(%q) = %v, %v, want %v, error %v", tt.text, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	store, clock := newTestStore()
+	limit := Limit{Count: 3, Period: time.Minute}
+
+	for i := 0; i < 3; i++ {
+		if allowed, _, _ := store.Take("ip:1", limit); !allowed {
+			t.Fatalf("take %d should be allowed", i+1)
+		}
+	}
+	allowed, retryAfter, err := store.Take("ip:1", limit)
+	if err != nil || allowed {
+		t.Fatalf("Take() = %v, %v, the bucket should be empty", allowed, err)
+	}
+	if retryAfter != 20*time.Second {
+		t.Errorf("retryAfter = %v, want 20s", retryAfter)
+	}
+	if allowed, _, _ := store.Take("ip:2", limit); !allowed {
+		t.Error("other keys have their own buckets")
+	}
+
+	// A token is added every period / count.
+	clock.Advance(15 * time.Second)
+	if allowed, retryAfter, _ := store.Take("ip:1", limit); allowed || retryAfter != 5*time.Second {
+		t.Errorf("Take() = %v, %v, want retry after 5s", allowed, retryAfter)
+	}
+	clock.Advance(5 * time.Second)
+	if allowed, _, _ := store.Take("ip:1", limit); !allowed {
+		t.Error("the bucket should have been refilled with a token")
+	}
+
+	// Unused tokens accumulate up to the count.
+	clock.Advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		if allowed, _, _ := store.Take("ip:1", limit); !allowed {
+			t.Fatalf("take %d after the refill should be allowed", i+1)
+		}
+	}
+	if allowed, _, _ := store.Take("ip:1", limit); allowed {
+		t.Error("the bucket should not hold more than the count")
+	}
+}
+
+func TestMemoryStorePeek(t *testing.T) {
+	store, _ := newTestStore()
+	limit := Limit{Count: 1, Period: time.Minute}
+
+	for i := 0; i < 2; i++ {
+		if allowed, _, _ := store.Peek("ip:1", limit); !allowed {
+			t.Fatalf("peek %d should be allowed without taking a token", i+1)
+		}
+	}
+	store.Take("ip:1", limit)
+	allowed, retryAfter, _ := store.Peek("ip:1", limit)
+	if allowed || retryAfter != time.Minute {
+		t.Errorf("Peek() = %v, %v, want retry after 1m", allowed, retryAfter)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store, clock := newTestStore()
+	limit := Limit{Count: 2, Period: time.Minute}
+	store.Take("ip:1", limit)
+	store.Take("ip:2", limit)
+	store.Take("ip:2", limit)
+
+	clock.Advance(90 * time.Second)
+	store.Take("ip:3", limit)
+	if _, ok := store.buckets["ip:1"]; ok {
+		t.Error("full buckets should be removed")
+	}
+	if _, ok := store.buckets["ip:3"]; !ok {
+		t.Error("the bucket that was just used should be kept")
+	}
+}
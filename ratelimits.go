@@ -0,0 +1,77 @@
+package sail
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/demianbucik/sail/config"
+	"github.com/demianbucik/sail/ratelimit"
+)
+
+// RateLimitStore keeps the rate limit buckets, set it before the first request to share the limits
+// between instances. By default, every instance keeps its own buckets in memory.
+var RateLimitStore ratelimit.Store
+
+// rateLimitError is returned for submissions over one of the limits.
+type rateLimitError struct {
+	key        string
+	retryAfter time.Duration
+}
+
+func (err *rateLimitError) Error() string {
+	return fmt.Sprintf("rate limit of '%s' exceeded, retry after %s", err.key, err.retryAfter.Round(time.Second))
+}
+
+// checkRateLimits takes a token from the buckets of the client IP, the submitted email address and the form.
+// Tokens are only taken when every bucket has one, so that a client over its IP limit can't use up the tokens
+// of someone else's email address. When the store fails, the submission is let through, so that an outage of
+// a shared store doesn't stop all submissions.
+func (service *sailService) checkRateLimits(formConf *config.Form, form *EmailForm, clientIp string) error {
+	type keyedLimit struct {
+		key   string
+		limit ratelimit.Limit
+	}
+	var limits []keyedLimit
+	add := func(key string, limit ratelimit.Limit) {
+		if limit.Enabled() {
+			limits = append(limits, keyedLimit{key, limit})
+		}
+	}
+	add("ip:"+clientIp, service.env.RateLimitIP)
+	// Forms without an email field are only limited by IP and form.
+	if form.Email != "" {
+		add("email:"+strings.ToLower(form.Email), service.env.RateLimitEmail)
+	}
+	add("form:"+formConf.ID, service.env.RateLimitForm)
+
+	var limitErr *rateLimitError
+	for _, l := range limits {
+		allowed, retryAfter, err := service.rateLimits.Peek(l.key, l.limit)
+		if err != nil {
+			log.WithError(err).WithField("key", l.key).Warn("Rate limit store failed")
+			continue
+		}
+		if !allowed && (limitErr == nil || retryAfter > limitErr.retryAfter) {
+			limitErr = &rateLimitError{key: l.key, retryAfter: retryAfter}
+		}
+	}
+	if limitErr != nil {
+		return limitErr
+	}
+
+	for _, l := range limits {
+		allowed, retryAfter, err := service.rateLimits.Take(l.key, l.limit)
+		if err != nil {
+			log.WithError(err).WithField("key", l.key).Warn("Rate limit store failed")
+			continue
+		}
+		// Another submission took the last token in the meantime.
+		if !allowed {
+			return &rateLimitError{key: l.key, retryAfter: retryAfter}
+		}
+	}
+	return nil
+}
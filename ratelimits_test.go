@@ -0,0 +1,78 @@
+package sail
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCheckRateLimits(t *testing.T) {
+	service, _ := newTestService(t, `
+RATE_LIMIT_IP: "1/1h"
+RATE_LIMIT_EMAIL: "2/1h"
+`)
+	formConf, _ := service.env.Form("")
+
+	tests := []struct {
+		ip, email string
+		wantKey   string
+	}{
+		{"1.1.1.1", "bob@example.com", ""},
+		// Over the IP limit, the email bucket must keep its last token.
+		{"1.1.1.1", "bob@example.com", "ip:1.1.1.1"},
+		{"2.2.2.2", "Bob@example.com", ""},
+		{"3.3.3.3", "bob@example.com", "email:bob@example.com"},
+		// Over the email limit, the IP bucket must keep its token.
+		{"3.3.3.3", "alice@example.com", ""},
+	}
+	for i, tt := range tests {
+		err := service.checkRateLimits(formConf, &EmailForm{Email: tt.email}, tt.ip)
+		var limitErr *rateLimitError
+		if tt.wantKey == "" {
+			if err != nil {
+				t.Fatalf("submission %d: checkRateLimits() = %v, want allowed", i+1, err)
+			}
+			continue
+		}
+		if !errors.As(err, &limitErr) || limitErr.key != tt.wantKey {
+			t.Fatalf("submission %d: checkRateLimits() = %v, want limit of '%s'", i+1, err, tt.wantKey)
+		}
+		if limitErr.retryAfter <= 0 || limitErr.retryAfter > time.Hour {
+			t.Errorf("submission %d: retryAfter = %v", i+1, limitErr.retryAfter)
+		}
+	}
+}
+
+func TestRateLimitResponse(t *testing.T) {
+	service, sent := newTestService(t, `
+RATE_LIMIT_IP: "2/1h"
+CONFIRMATION_MODE: "off"
+`)
+	values := "name=Bob&email=bob@example.com&subject=Hi&message=Hello"
+
+	for i := 0; i < 2; i++ {
+		if resp := submit(service, values, "X-Forwarded-For", "1.1.1.1"); resp.Code != http.StatusOK {
+			t.Fatalf("submission %d: status = %d, body %s", i+1, resp.Code, resp.Body)
+		}
+	}
+	resp := submit(service, values, "X-Forwarded-For", "1.1.1.1")
+	if resp.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", resp.Code, http.StatusTooManyRequests)
+	}
+	if got := resp.Header().Get("Retry-After"); got != "1800" {
+		t.Errorf("Retry-After = %q, want %q", got, "1800")
+	}
+	if sent.count() != 2 {
+		t.Errorf("sent %d emails, want 2", sent.count())
+	}
+
+	// The address forged by the client in front of the proxy's doesn't get a new bucket.
+	resp = submit(service, values, "X-Forwarded-For", "9.9.9.9, 1.1.1.1")
+	if resp.Code != http.StatusTooManyRequests {
+		t.Errorf("status with a forged address = %d, want %d", resp.Code, http.StatusTooManyRequests)
+	}
+	if resp := submit(service, values, "X-Forwarded-For", "2.2.2.2"); resp.Code != http.StatusOK {
+		t.Errorf("status of another client = %d, body %s", resp.Code, resp.Body)
+	}
+}
@@ -0,0 +1,68 @@
+package sail
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/demianbucik/sail/config"
+)
+
+// submissionResponse is returned to AJAX and fetch submissions instead of a redirect.
+type submissionResponse struct {
+	Status       string            `json:"status"`
+	Code         int               `json:"code"`
+	SubmissionId string            `json:"submissionId"`
+	Message      string            `json:"message,omitempty"`
+	Errors       map[string]string `json:"errors,omitempty"`
+	// RetryAfter is the number of seconds until a rate limited client may submit again.
+	RetryAfter int       `json:"retryAfter,omitempty"`
+	Delivery   *delivery `json:"delivery,omitempty"`
+}
+
+// wantsJSON reports whether the client asked for a JSON response, either with
+// the Accept header or the "format=json" query parameter.
+func wantsJSON(request *http.Request) bool {
+	if request.URL.Query().Get("format") == "json" {
+		return true
+	}
+	return strings.Contains(request.Header.Get("Accept"), "application/json")
+}
+
+// respond writes a JSON response or redirects to the success or error page of the form, depending on the request.
+// Without a form the top-level pages are used, a JSON response is written when there is no page to redirect to.
+// Rate limited clients are redirected to RATE_LIMIT_PAGE, when it's set.
+func (service *sailService) respond(writer http.ResponseWriter, request *http.Request, formConf *config.Form, resp *submissionResponse) {
+	if !wantsJSON(request) {
+		successPage, errorPage := service.env.SuccessPage, service.env.ErrorPage
+		if formConf != nil {
+			successPage, errorPage = formConf.SuccessPage, formConf.ErrorPage
+		}
+		page := successPage
+		if resp.Code == http.StatusTooManyRequests && service.env.RateLimitPage != "" {
+			page = service.env.RateLimitPage
+		} else if resp.Code >= 400 {
+			page = errorPage
+		}
+		if page != "" {
+			http.Redirect(writer, request, page, http.StatusSeeOther)
+			return
+		}
+	}
+
+	resp.Status = "ok"
+	if resp.Code >= 400 {
+		resp.Status = "error"
+	}
+	if resp.Message == "" {
+		resp.Message = http.StatusText(resp.Code)
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	if resp.RetryAfter > 0 {
+		writer.Header().Set("Retry-After", strconv.Itoa(resp.RetryAfter))
+	}
+	writer.WriteHeader(resp.Code)
+	_ = json.NewEncoder(writer).Encode(resp)
+}
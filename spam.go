@@ -0,0 +1,69 @@
+package sail
+
+import (
+	"github.com/apex/log"
+
+	"github.com/demianbucik/sail/config"
+	"github.com/demianbucik/sail/spam"
+)
+
+// quarantineSubjectPrefix marks the emails of quarantined submissions.
+const quarantineSubjectPrefix = "[Quarantine] "
+
+// SpamHashStore remembers the submitted messages for the duplicate spam rule and the used time-trap tokens,
+// set it before the first request to share them with other instances. By default, every instance keeps its own
+// hashes in memory.
+var SpamHashStore spam.HashStore
+
+type spamVerdict string
+
+const (
+	spamAccept     spamVerdict = "accept"
+	spamQuarantine spamVerdict = "quarantine"
+	spamReject     spamVerdict = "reject"
+)
+
+// spamCheck is the spam score of a submission and what happens to it.
+type spamCheck struct {
+	spam.Result
+	Verdict spamVerdict `json:"verdict"`
+}
+
+func (check *spamCheck) quarantined() bool {
+	return check != nil && check.Verdict == spamQuarantine
+}
+
+func (check *spamCheck) rejected() bool {
+	return check != nil && check.Verdict == spamReject
+}
+
+// checkSpam scores the submission with the spam rules of the form, it returns nil when the form has none.
+// When the hash store fails, duplicates aren't detected, but the other rules still count.
+func (service *sailService) checkSpam(formConf *config.Form, form *EmailForm) *spamCheck {
+	if !formConf.SpamScoringEnabled() {
+		return nil
+	}
+	result, err := formConf.SpamRules.Score(form.Fields, service.spamHashes)
+	if err != nil {
+		log.WithError(err).WithField("submissionId", form.SubmissionId).Warn("Spam hash store failed")
+	}
+
+	check := &spamCheck{Result: result, Verdict: spamAccept}
+	switch {
+	case formConf.SpamRejectScore > 0 && result.Score >= float64(formConf.SpamRejectScore):
+		check.Verdict = spamReject
+	case formConf.SpamQuarantineScore > 0 && result.Score >= float64(formConf.SpamQuarantineScore):
+		check.Verdict = spamQuarantine
+	}
+	return check
+}
+
+// recordSpamHashes remembers the values of a delivered submission for the duplicate spam rule.
+func (service *sailService) recordSpamHashes(form *EmailForm) {
+	if form.Spam == nil {
+		return
+	}
+	if err := form.Spam.Record(service.spamHashes); err != nil {
+		log.WithError(err).WithField("submissionId", form.SubmissionId).Warn("Spam hash store failed")
+	}
+}
@@ -0,0 +1,186 @@
+// Package spam scores form submissions with content heuristics, such as the number of links in the message
+// or blocked keywords. Every matching rule adds its score, the total decides what happens to the submission.
+package spam
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type RuleType string
+
+const (
+	// RuleLinks scores every link above Max.
+	RuleLinks RuleType = "links"
+	// RuleKeywords scores every keyword found as a whole word, ignoring the case.
+	RuleKeywords RuleType = "keywords"
+	// RuleRegex scores values matching Regex.
+	RuleRegex RuleType = "regex"
+	// RuleCaps scores values with a larger share of uppercase letters than Ratio.
+	RuleCaps RuleType = "caps"
+	// RuleScript scores values with a larger share of letters in one of Scripts than Ratio.
+	RuleScript RuleType = "script"
+	// RuleDuplicate scores values that were already submitted within Window.
+	RuleDuplicate RuleType = "duplicate"
+	// RuleNameEmail scores names that have nothing in common with the email address, or contain links and addresses.
+	RuleNameEmail RuleType = "name-email"
+)
+
+const (
+	defaultCapsRatio   = 0.6
+	defaultScriptRatio = 0.3
+	defaultWindow      = 24 * time.Hour
+)
+
+// scriptAliases groups the scripts that are usually expected or unexpected together.
+var scriptAliases = map[string][]string{
+	"cjk": {"Han", "Hiragana", "Katakana", "Hangul"},
+}
+
+// Rule is a single heuristic with the score it adds when it matches.
+type Rule struct {
+	Type  RuleType `yaml:"rule"`
+	Score float64  `yaml:"score"`
+	// Fields checked by the rule, the message by default. The name-email rule always checks the name and email fields.
+	Fields []string `yaml:"fields"`
+
+	Max      int           `yaml:"max"`
+	Keywords []string      `yaml:"keywords"`
+	Regex    string        `yaml:"regex"`
+	Ratio    float64       `yaml:"ratio"`
+	Scripts  []string      `yaml:"scripts"`
+	Window   time.Duration `yaml:"window"`
+
+	regex    *regexp.Regexp
+	keywords []*regexp.Regexp
+	scripts  []*unicode.RangeTable
+}
+
+// CheckedFields returns the fields the rule checks.
+func (rule *Rule) CheckedFields() []string {
+	switch {
+	case rule.Type == RuleNameEmail:
+		return []string{"name", "email"}
+	case len(rule.Fields) == 0:
+		return []string{"message"}
+	}
+	return rule.Fields
+}
+
+func (rule *Rule) ratio(fallback float64) float64 {
+	if rule.Ratio == 0 {
+		return fallback
+	}
+	return rule.Ratio
+}
+
+func (rule *Rule) window() time.Duration {
+	if rule.Window == 0 {
+		return defaultWindow
+	}
+	return rule.Window
+}
+
+// compile checks the settings of the rule and prepares its patterns.
+func (rule *Rule) compile() error {
+	if rule.Score <= 0 {
+		return fmt.Errorf("score should be positive")
+	}
+	if rule.Ratio < 0 || rule.Ratio > 1 {
+		return fmt.Errorf("ratio '%v' should be between 0 and 1", rule.Ratio)
+	}
+	switch rule.Type {
+	case RuleLinks:
+		if rule.Max < 0 {
+			return fmt.Errorf("max should not be negative")
+		}
+	case RuleKeywords:
+		if len(rule.Keywords) == 0 {
+			return fmt.Errorf("keywords should not be empty")
+		}
+		rule.keywords = nil
+		for _, keyword := range rule.Keywords {
+			keyword = strings.TrimSpace(keyword)
+			if keyword == "" {
+				return fmt.Errorf("keywords should not be empty")
+			}
+			pattern := `(?i)(?:^|[^\pL\pN])` + regexp.QuoteMeta(keyword) + `(?:[^\pL\pN]|$)`
+			rule.keywords = append(rule.keywords, regexp.MustCompile(pattern))
+		}
+	case RuleRegex:
+		if rule.Regex == "" {
+			return fmt.Errorf("regex should not be empty")
+		}
+		regex, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+		rule.regex = regex
+	case RuleScript:
+		if len(rule.Scripts) == 0 {
+			return fmt.Errorf("scripts should not be empty")
+		}
+		rule.scripts = nil
+		for _, name := range rule.Scripts {
+			tables, ok := lookupScript(name)
+			if !ok {
+				return fmt.Errorf("unknown script '%s', use Unicode script names such as 'Cyrillic' or 'CJK'", name)
+			}
+			rule.scripts = append(rule.scripts, tables...)
+		}
+	case RuleDuplicate:
+		if rule.Window < 0 {
+			return fmt.Errorf("window should not be negative")
+		}
+	case RuleCaps, RuleNameEmail:
+	default:
+		return fmt.Errorf("unknown rule '%s', valid options are 'links', 'keywords', 'regex', 'caps', 'script', 'duplicate' and 'name-email'", rule.Type)
+	}
+	return nil
+}
+
+// lookupScript returns the Unicode tables of a script name, ignoring the case.
+func lookupScript(name string) ([]*unicode.RangeTable, bool) {
+	name = strings.TrimSpace(name)
+	if aliases, ok := scriptAliases[strings.ToLower(name)]; ok {
+		tables := make([]*unicode.RangeTable, len(aliases))
+		for i, alias := range aliases {
+			tables[i] = unicode.Scripts[alias]
+		}
+		return tables, true
+	}
+	for script, table := range unicode.Scripts {
+		if strings.EqualFold(script, name) {
+			return []*unicode.RangeTable{table}, true
+		}
+	}
+	return nil, false
+}
+
+// Rules is a list of rules, the total score of a submission is the sum of the matching ones.
+type Rules []Rule
+
+// Validate checks the rules without preparing their patterns, so that rules shared by several forms
+// are only changed by Compile.
+func (rules Rules) Validate() error {
+	for i := range rules {
+		rule := rules[i]
+		if err := rule.compile(); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Compile checks the rules and prepares their patterns, it has to be called before Score.
+func (rules Rules) Compile() error {
+	for i := range rules {
+		if err := rules[i].compile(); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
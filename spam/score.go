@@ -0,0 +1,205 @@
+package spam
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// minLetters is the number of letters a value needs before its share of uppercase or foreign letters counts,
+// so that short values such as "OK" or a single foreign name don't match.
+const minLetters = 12
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"']+`)
+
+// Hit is a rule that matched, with the score it added.
+type Hit struct {
+	Rule   RuleType `json:"rule"`
+	Score  float64  `json:"score"`
+	Reason string   `json:"reason"`
+}
+
+// Result is the total score of a submission together with the rules that matched.
+type Result struct {
+	Score float64 `json:"score"`
+	Hits  []Hit   `json:"hits,omitempty"`
+	// duplicates are the hashes checked by the duplicate rules, see Record.
+	duplicates []duplicate
+}
+
+type duplicate struct {
+	hash   string
+	window time.Duration
+}
+
+// Record records the values checked by the duplicate rules, once the submission is delivered,
+// so that rejected or failed submissions don't count as duplicates of the ones sent again.
+func (result *Result) Record(hashes HashStore) error {
+	var storeErr error
+	for _, d := range result.duplicates {
+		if _, err := hashes.Record(d.hash, d.window); err != nil {
+			storeErr = err
+		}
+	}
+	return storeErr
+}
+
+func (result *Result) add(rule *Rule, times int, reason string, args ...any) {
+	score := rule.Score * float64(times)
+	result.Score += score
+	result.Hits = append(result.Hits, Hit{Rule: rule.Type, Score: score, Reason: fmt.Sprintf(reason, args...)})
+}
+
+// Score runs the rules over the submitted fields. Duplicates are only detected with a store, the other rules
+// still score when the store fails, the error is returned together with the result. The submitted values are
+// not recorded, see Result.Record.
+func (rules Rules) Score(fields map[string]string, hashes HashStore) (Result, error) {
+	var result Result
+	var storeErr error
+	for i := range rules {
+		rule := &rules[i]
+		text := joinFields(fields, rule.CheckedFields())
+
+		switch rule.Type {
+		case RuleLinks:
+			if links := len(linkPattern.FindAllString(text, -1)); links > rule.Max {
+				result.add(rule, links-rule.Max, "%d links, more than %d", links, rule.Max)
+			}
+		case RuleKeywords:
+			var found []string
+			for j, keyword := range rule.keywords {
+				if keyword.MatchString(text) {
+					found = append(found, rule.Keywords[j])
+				}
+			}
+			if len(found) > 0 {
+				result.add(rule, len(found), "keywords '%s'", strings.Join(found, "', '"))
+			}
+		case RuleRegex:
+			// A regex that hasn't been compiled fails closed, the submission is scored as if it matched.
+			if rule.regex == nil {
+				result.add(rule, 1, "regex '%s' is not compiled", rule.Regex)
+			} else if rule.regex.MatchString(text) {
+				result.add(rule, 1, "matches '%s'", rule.Regex)
+			}
+		case RuleCaps:
+			if ratio, ok := letterRatio(withoutLinks(text), unicode.IsUpper); ok && ratio > rule.ratio(defaultCapsRatio) {
+				result.add(rule, 1, "%.0f%% uppercase letters", ratio*100)
+			}
+		case RuleScript:
+			inScripts := func(r rune) bool { return unicode.IsOneOf(rule.scripts, r) }
+			if ratio, ok := letterRatio(withoutLinks(text), inScripts); ok && ratio > rule.ratio(defaultScriptRatio) {
+				result.add(rule, 1, "%.0f%% letters in %s", ratio*100, strings.Join(rule.Scripts, ", "))
+			}
+		case RuleDuplicate:
+			if hashes == nil || strings.TrimSpace(text) == "" {
+				continue
+			}
+			hash := hashText(text)
+			result.duplicates = append(result.duplicates, duplicate{hash: hash, window: rule.window()})
+			seen, err := hashes.Seen(hash, rule.window())
+			if err != nil {
+				storeErr = err
+				continue
+			}
+			if seen {
+				result.add(rule, 1, "submitted before within %s", rule.window())
+			}
+		case RuleNameEmail:
+			if reason := nameEmailMismatch(fields["name"], fields["email"]); reason != "" {
+				result.add(rule, 1, "%s", reason)
+			}
+		}
+	}
+	return result, storeErr
+}
+
+func joinFields(fields map[string]string, names []string) string {
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		if value := fields[name]; value != "" {
+			values = append(values, value)
+		}
+	}
+	return strings.Join(values, "\n")
+}
+
+func withoutLinks(text string) string {
+	return linkPattern.ReplaceAllString(text, " ")
+}
+
+// letterRatio returns the share of the letters that match, it's not ok for text with too few letters.
+func letterRatio(text string, match func(rune) bool) (float64, bool) {
+	letters, matches := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if match(r) {
+			matches++
+		}
+	}
+	if letters < minLetters {
+		return 0, false
+	}
+	return float64(matches) / float64(letters), true
+}
+
+// hashText hashes the text with the case and whitespace normalized, so that small changes don't hide a duplicate.
+func hashText(text string) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(text)), " ")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// nameEmailMismatch returns why the name doesn't fit the email address, or an empty string when it does.
+// Names fit when one of their words is part of the local part of the address, or the other way around.
+// Short names and names with letters outside of ASCII, such as "Jo" or "Müller", aren't judged.
+func nameEmailMismatch(name, email string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || email == "" {
+		return ""
+	}
+	if linkPattern.MatchString(name) || strings.Contains(name, "@") {
+		return "name contains a link or an email address"
+	}
+
+	localPart, _, _ := strings.Cut(strings.ToLower(email), "@")
+	localLetters := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return r
+		}
+		return -1
+	}, localPart)
+	// Addresses such as j.d@example.com or 12345@example.com don't tell much about the name.
+	if len([]rune(localLetters)) < 3 {
+		return ""
+	}
+
+	words := strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) })
+	comparable := false
+	for _, word := range words {
+		if strings.IndexFunc(word, func(r rune) bool { return r > unicode.MaxASCII }) >= 0 {
+			return ""
+		}
+		if len(word) < 3 {
+			continue
+		}
+		if strings.Contains(localLetters, word) {
+			return ""
+		}
+		comparable = true
+	}
+	if !comparable {
+		return ""
+	}
+	if strings.Contains(strings.Join(words, ""), localLetters) {
+		return ""
+	}
+	return fmt.Sprintf("name '%s' doesn't match address '%s'", name, email)
+}
@@ -0,0 +1,170 @@
+package spam
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func compiled(t *testing.T, rules ...Rule) Rules {
+	t.Helper()
+	if err := Rules(rules).Compile(); err != nil {
+		t.Fatal(err)
+	}
+	return rules
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name      string
+		rule      Rule
+		fields    map[string]string
+		wantScore float64
+	}{
+		{"links below max", Rule{Type: RuleLinks, Score: 1, Max: 1}, map[string]string{"message": "See https://example.com"}, 0},
+		{"every link above max", Rule{Type: RuleLinks, Score: 1, Max: 1},
+			map[string]string{"message": "https://a.com, http://b.com and www.c.com"}, 2},
+		{"links need a word boundary", Rule{Type: RuleLinks, Score: 1},
+			map[string]string{"message": "xhttps://a.com and awww.b.com"}, 0},
+
+		{"keyword", Rule{Type: RuleKeywords, Score: 2, Keywords: []string{"casino"}}, map[string]string{"message": "Best Casino!"}, 2},
+		{"keyword inside a word", Rule{Type: RuleKeywords, Score: 2, Keywords: []string{"casino", "sex"}},
+			map[string]string{"message": "casinos in Middlesex"}, 0},
+		{"every keyword", Rule{Type: RuleKeywords, Score: 2, Keywords: []string{"seo", "crypto offer"}},
+			map[string]string{"message": "SEO and a crypto offer."}, 4},
+		{"keyword in other fields", Rule{Type: RuleKeywords, Score: 2, Keywords: []string{"seo"}, Fields: []string{"subject"}},
+			map[string]string{"subject": "Hello", "message": "seo"}, 0},
+
+		{"regex", Rule{Type: RuleRegex, Score: 3, Regex: `\d{4}-\d{4}`}, map[string]string{"message": "Call 1234-5678"}, 3},
+		{"regex doesn't match", Rule{Type: RuleRegex, Score: 3, Regex: `\d{4}-\d{4}`}, map[string]string{"message": "Call me"}, 0},
+
+		{"caps", Rule{Type: RuleCaps, Score: 1}, map[string]string{"message": "BUY NOW AND SAVE MONEY"}, 1},
+		{"caps with too few letters", Rule{Type: RuleCaps, Score: 1}, map[string]string{"message": "HELLO WORLD"}, 0},
+		{"caps ignore links", Rule{Type: RuleCaps, Score: 1}, map[string]string{"message": "Hello there, see HTTPS://EXAMPLE.COM/ABCDEF"}, 0},
+		{"caps below ratio", Rule{Type: RuleCaps, Score: 1, Ratio: 0.9}, map[string]string{"message": "BUY NOW AND SAVE money"}, 0},
+
+		{"script", Rule{Type: RuleScript, Score: 1, Scripts: []string{"Cyrillic"}},
+			map[string]string{"message": "Привет, как у тебя дела"}, 1},
+		{"script with too few letters", Rule{Type: RuleScript, Score: 1, Scripts: []string{"Cyrillic"}},
+			map[string]string{"message": "Привет мир"}, 0},
+		{"script below ratio", Rule{Type: RuleScript, Score: 1, Scripts: []string{"cjk"}},
+			map[string]string{"message": "Greetings from 東京 to everyone"}, 0},
+
+		{"name matches email", Rule{Type: RuleNameEmail, Score: 1},
+			map[string]string{"name": "Bob Stone", "email": "bob.stone@example.com"}, 0},
+		{"name part of email", Rule{Type: RuleNameEmail, Score: 1},
+			map[string]string{"name": "Robert", "email": "robert1984@example.com"}, 0},
+		{"email part of name", Rule{Type: RuleNameEmail, Score: 1},
+			map[string]string{"name": "Alice Johnson", "email": "alicejohnson@example.com"}, 0},
+		{"name doesn't match email", Rule{Type: RuleNameEmail, Score: 1},
+			map[string]string{"name": "Crypto Deals", "email": "bob.stone@example.com"}, 1},
+		{"link in name", Rule{Type: RuleNameEmail, Score: 1},
+			map[string]string{"name": "www.deals.com", "email": "deals@example.com"}, 1},
+		{"short local part", Rule{Type: RuleNameEmail, Score: 1},
+			map[string]string{"name": "Crypto Deals", "email": "j.d@example.com"}, 0},
+		{"name outside of ASCII", Rule{Type: RuleNameEmail, Score: 1},
+			map[string]string{"name": "Jürgen Müller", "email": "info@example.com"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := compiled(t, tt.rule).Score(tt.fields, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Score != tt.wantScore {
+				t.Errorf("Score() = %v, want %v, hits %+v", result.Score, tt.wantScore, result.Hits)
+			}
+		})
+	}
+}
+
+func TestScoreAddsRules(t *testing.T) {
+	rules := compiled(t,
+		Rule{Type: RuleLinks, Score: 1.5, Max: 0},
+		Rule{Type: RuleKeywords, Score: 2, Keywords: []string{"casino"}},
+		Rule{Type: RuleCaps, Score: 1},
+	)
+	result, _ := rules.Score(map[string]string{"message": "Visit my casino at https://example.com"}, nil)
+	if result.Score != 3.5 || len(result.Hits) != 2 {
+		t.Errorf("Score() = %+v, want 3.5 from two rules", result)
+	}
+}
+
+func TestScoreUncompiledRegex(t *testing.T) {
+	rules := Rules{{Type: RuleRegex, Score: 3, Regex: "casino"}}
+	result, _ := rules.Score(map[string]string{"message": "Hello"}, nil)
+	if result.Score != 3 {
+		t.Errorf("Score() = %v, a regex that isn't compiled should count as a match", result.Score)
+	}
+}
+
+func TestScoreDuplicates(t *testing.T) {
+	rules := compiled(t, Rule{Type: RuleDuplicate, Score: 5, Window: time.Hour})
+	hashes := NewMemoryStore()
+	fields := map[string]string{"message": "Buy  cheap\nWatches"}
+
+	// Scoring alone doesn't record the message, so rejected or undelivered submissions can be sent again.
+	for i := 0; i < 2; i++ {
+		result, err := rules.Score(fields, hashes)
+		if err != nil || result.Score != 0 {
+			t.Fatalf("Score() = %v, %v, want no duplicate before Record", result.Score, err)
+		}
+	}
+
+	result, _ := rules.Score(fields, hashes)
+	if err := result.Record(hashes); err != nil {
+		t.Fatal(err)
+	}
+	// Case and whitespace don't hide a duplicate.
+	result, _ = rules.Score(map[string]string{"message": "buy cheap watches"}, hashes)
+	if result.Score != 5 || !strings.Contains(result.Hits[0].Reason, "1h0m0s") {
+		t.Errorf("Score() = %+v, want a duplicate", result)
+	}
+	result, _ = rules.Score(map[string]string{"message": "Buy cheap clocks"}, hashes)
+	if result.Score != 0 {
+		t.Errorf("Score() = %v, want no duplicate of another message", result.Score)
+	}
+	result, _ = rules.Score(map[string]string{"message": " "}, hashes)
+	if len(result.duplicates) != 0 {
+		t.Error("empty messages should not be recorded")
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr string
+	}{
+		{"valid", Rule{Type: RuleKeywords, Score: 1, Keywords: []string{"a"}}, ""},
+		{"zero score", Rule{Type: RuleCaps}, "score should be positive"},
+		{"unknown rule", Rule{Type: "typos", Score: 1}, "unknown rule 'typos'"},
+		{"empty keyword", Rule{Type: RuleKeywords, Score: 1, Keywords: []string{" "}}, "keywords should not be empty"},
+		{"invalid regex", Rule{Type: RuleRegex, Score: 1, Regex: "("}, "invalid regex"},
+		{"unknown script", Rule{Type: RuleScript, Score: 1, Scripts: []string{"Klingon"}}, "unknown script 'Klingon'"},
+		{"invalid ratio", Rule{Type: RuleCaps, Score: 1, Ratio: 2}, "ratio '2' should be between 0 and 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := Rules{tt.rule}
+			err := rules.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	rules := Rules{{Type: RuleRegex, Score: 1, Regex: "a"}}
+	if err := rules.Validate(); err != nil || rules[0].regex != nil {
+		t.Errorf("Validate() = %v, it should not prepare the patterns", err)
+	}
+	if err := rules.Compile(); err != nil || rules[0].regex == nil {
+		t.Errorf("Compile() = %v, it should prepare the patterns", err)
+	}
+}
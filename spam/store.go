@@ -0,0 +1,75 @@
+//go:generate mockery --inpackage --name=HashStore
+
+package spam
+
+import (
+	"sync"
+	"time"
+)
+
+// HashStore remembers the hashes of submitted messages to detect duplicates. A store shared by several instances,
+// for example in Redis, detects duplicates sent to any of them.
+type HashStore interface {
+	// Seen reports whether the hash was recorded within the window.
+	Seen(hash string, window time.Duration) (bool, error)
+	// Record records the hash and reports whether it was already recorded within the window.
+	// It has to be atomic, so that values such as time-trap tokens can be reserved by one submission only.
+	Record(hash string, window time.Duration) (bool, error)
+	// Forget removes the hash, so that a reserved value can be used again.
+	Forget(hash string) error
+}
+
+// MemoryStore keeps the hashes in memory, so every instance detects its own duplicates.
+// Hashes older than the longest window are removed periodically.
+type MemoryStore struct {
+	mu        sync.Mutex
+	hashes    map[string]time.Time
+	maxWindow time.Duration
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{hashes: make(map[string]time.Time), lastSweep: time.Now()}
+}
+
+func (s *MemoryStore) Seen(hash string, window time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last, ok := s.hashes[hash]
+	return ok && time.Since(last) <= window, nil
+}
+
+func (s *MemoryStore) Record(hash string, window time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if window > s.maxWindow {
+		s.maxWindow = window
+	}
+	if now.Sub(s.lastSweep) > time.Minute {
+		s.sweep(now)
+	}
+
+	last, ok := s.hashes[hash]
+	s.hashes[hash] = now
+	return ok && now.Sub(last) <= window, nil
+}
+
+func (s *MemoryStore) Forget(hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.hashes, hash)
+	return nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for hash, last := range s.hashes {
+		if now.Sub(last) > s.maxWindow {
+			delete(s.hashes, hash)
+		}
+	}
+	s.lastSweep = now
+}
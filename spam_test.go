@@ -0,0 +1,89 @@
+package sail
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/demianbucik/sail/mailer"
+)
+
+const spamSettings = `
+CONFIRMATION_MODE: "off"
+SPAM_QUARANTINE_SCORE: "2"
+SPAM_REJECT_SCORE: "5"
+SPAM_QUARANTINE_EMAIL: "Spam Box <spam@mydomain.com>"
+SPAM_RULES: |
+  - rule: keywords
+    score: 2
+    keywords: [casino]
+  - rule: links
+    score: 1
+    max: 0
+  - rule: duplicate
+    score: 5
+`
+
+func TestCheckSpam(t *testing.T) {
+	tests := []struct {
+		name        string
+		settings    string
+		message     string
+		wantVerdict spamVerdict
+	}{
+		{"below the quarantine score", spamSettings, "See https://example.com", spamAccept},
+		{"at the quarantine score", spamSettings, "Best casino", spamQuarantine},
+		{"below the reject score", spamSettings, "Best casino at https://a.com and https://b.com", spamQuarantine},
+		{"at the reject score", spamSettings, "Casino at https://a.com, https://b.com and https://c.com", spamReject},
+		{"without a quarantine score", strings.Replace(spamSettings, `SPAM_QUARANTINE_SCORE: "2"`, "", 1), "Best casino", spamAccept},
+		{"without a reject score", strings.Replace(spamSettings, `SPAM_REJECT_SCORE: "5"`, "", 1), "Casino at https://a.com, https://b.com and https://c.com", spamQuarantine},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService(t, tt.settings)
+			formConf, _ := service.env.Form("")
+
+			check := service.checkSpam(formConf, &EmailForm{Fields: map[string]string{"message": tt.message}})
+			if check == nil || check.Verdict != tt.wantVerdict {
+				t.Errorf("checkSpam() = %+v, want %s", check, tt.wantVerdict)
+			}
+		})
+	}
+}
+
+func TestSpamSubmissions(t *testing.T) {
+	service, sent := newTestService(t, spamSettings)
+	values := "name=Bob&email=bob@example.com&subject=Hi&message="
+
+	// Quarantined submissions go to the quarantine address only.
+	if resp := submit(service, values+"Best+casino"); resp.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", resp.Code, resp.Body)
+	}
+	email := sent.last()
+	if len(email.To) != 1 || email.To[0] != mailer.NewAddress("Spam Box", "spam@mydomain.com") ||
+		!strings.HasPrefix(email.Subject, quarantineSubjectPrefix) {
+		t.Errorf("quarantined email to %v with subject %q", email.To, email.Subject)
+	}
+
+	// A submission that failed to send isn't recorded, so it can be sent again.
+	sent.fail(errors.New("connection refused"))
+	if resp := submit(service, values+"Hello"); resp.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, body %s", resp.Code, resp.Body)
+	}
+	sent.fail(nil)
+	if resp := submit(service, values+"Hello"); resp.Code != http.StatusOK {
+		t.Fatalf("status of the second attempt = %d, body %s", resp.Code, resp.Body)
+	}
+	if email := sent.last(); strings.HasPrefix(email.Subject, quarantineSubjectPrefix) {
+		t.Errorf("the second attempt should not be a duplicate, subject %q", email.Subject)
+	}
+
+	// Once delivered, the same message is rejected as a duplicate.
+	if resp := submit(service, values+"Hello"); resp.Code != http.StatusForbidden {
+		t.Errorf("status of the duplicate = %d, want %d", resp.Code, http.StatusForbidden)
+	}
+	if sent.count() != 2 {
+		t.Errorf("sent %d emails, want 2", sent.count())
+	}
+}
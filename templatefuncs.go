@@ -0,0 +1,77 @@
+package sail
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	htmltemplate "html/template"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+)
+
+// templateFuncs are available in the email, subject and sender name templates.
+// Functions returning HTML aren't escaped again by html/template.
+var templateFuncs = map[string]any{
+	"formatDate": formatDate,
+	"truncate":   truncate,
+	"default":    defaultValue,
+	"nl2br":      nl2br,
+	"markdown":   markdown,
+}
+
+// formatDate formats a time or a date field value, for example {{ .TIMESTAMP | formatDate "02 Jan 2006 15:04" }}.
+func formatDate(layout string, value any) (string, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(layout), nil
+	case string:
+		if v == "" {
+			return "", nil
+		}
+		date, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return "", err
+		}
+		return date.Format(layout), nil
+	default:
+		return "", fmt.Errorf("formatDate: unsupported value of type %T", value)
+	}
+}
+
+// truncate shortens the value to at most length characters, for example {{ .FORM_MESSAGE | truncate 100 }}.
+func truncate(length int, value string) string {
+	if utf8.RuneCountInString(value) <= length {
+		return value
+	}
+	runes := []rune(value)
+	if length < 1 {
+		return ""
+	}
+	return string(runes[:length-1]) + "…"
+}
+
+// defaultValue returns the fallback for empty values, for example {{ .FORM_PHONE | default "not provided" }}.
+func defaultValue(fallback, value string) string {
+	if strings.TrimSpace(value) == "" {
+		return fallback
+	}
+	return value
+}
+
+// nl2br escapes the value and replaces the line breaks with <br> tags.
+func nl2br(value string) htmltemplate.HTML {
+	value = strings.ReplaceAll(value, "\r\n", "\n")
+	return htmltemplate.HTML(strings.ReplaceAll(html.EscapeString(value), "\n", "<br>\n"))
+}
+
+// markdown converts the value to HTML, raw HTML in the value is left out.
+func markdown(value string) (htmltemplate.HTML, error) {
+	buf := &bytes.Buffer{}
+	if err := goldmark.Convert([]byte(value), buf); err != nil {
+		return "", err
+	}
+	return htmltemplate.HTML(buf.String()), nil
+}
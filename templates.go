@@ -0,0 +1,287 @@
+package sail
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+
+	"github.com/demianbucik/sail/config"
+	"github.com/demianbucik/sail/cssinline"
+)
+
+// templateExts are the extensions of the files parsed as templates, other files in the templates directory,
+// such as images or editor backups, are ignored.
+var templateExts = []string{".html", ".htm", ".txt", ".tmpl"}
+
+// templateSet renders HTML templates with html/template, so that submitted values are escaped,
+// and all other templates with text/template. Templates are selected by their file name.
+type templateSet struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+	// inlineCSS moves the style sheet rules of rendered HTML templates into style attributes.
+	inlineCSS bool
+}
+
+// parseTemplates parses the template files in the directories, files in later directories replace
+// the files with the same name in earlier ones. The file extension decides the template package.
+func parseTemplates(dirs ...fs.FS) (*templateSet, error) {
+	files, err := readTemplateFiles(dirs...)
+	if err != nil {
+		return nil, err
+	}
+	set := newTemplateSet()
+	for name, content := range files {
+		if err = set.parse(name, content); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+func readTemplateFiles(dirs ...fs.FS) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	for _, dir := range dirs {
+		entries, err := fs.ReadDir(dir, ".")
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !isTemplateFile(entry.Name()) {
+				continue
+			}
+			if files[entry.Name()], err = fs.ReadFile(dir, entry.Name()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return files, nil
+}
+
+func newTemplateSet() *templateSet {
+	return &templateSet{
+		text: texttemplate.New("").Funcs(templateFuncs).Option("missingkey=error"),
+		html: htmltemplate.New("").Funcs(templateFuncs).Option("missingkey=error"),
+	}
+}
+
+func (set *templateSet) parse(name string, content []byte) error {
+	var err error
+	if isHTMLTemplate(name) {
+		_, err = set.html.New(name).Parse(string(content))
+	} else {
+		_, err = set.text.New(name).Parse(string(content))
+	}
+	return err
+}
+
+// execute renders the named template and reports whether the result is HTML.
+func (set *templateSet) execute(name string, data any) ([]byte, bool, error) {
+	buf := &bytes.Buffer{}
+	if !set.has(name) {
+		return nil, false, fmt.Errorf("template '%s' not found", name)
+	}
+	if isHTMLTemplate(name) {
+		if err := set.html.ExecuteTemplate(buf, name, data); err != nil || !set.inlineCSS {
+			return buf.Bytes(), true, err
+		}
+		body, err := cssinline.Inline(buf.String())
+		return []byte(body), true, err
+	}
+	err := set.text.ExecuteTemplate(buf, name, data)
+	return buf.Bytes(), false, err
+}
+
+func (set *templateSet) has(name string) bool {
+	if isHTMLTemplate(name) {
+		return set.html.Lookup(name) != nil
+	}
+	return set.text.Lookup(name) != nil
+}
+
+// executeAlternatives renders the named template together with its plain-text or HTML counterpart,
+// a template with the same name and a ".txt" or ".html" extension, if there is one. Otherwise the fallback
+// template or its counterpart of the other type is used, this pairs a localized template with the default one.
+func (set *templateSet) executeAlternatives(name, fallback string, data any) (string, string, error) {
+	names := []string{name}
+	if counterpart, ok := set.counterpart(name); ok {
+		names = append(names, counterpart)
+	} else if fallback != name {
+		if isHTMLTemplate(fallback) != isHTMLTemplate(name) && set.has(fallback) {
+			names = append(names, fallback)
+		} else if counterpart, ok := set.counterpart(fallback); ok && isHTMLTemplate(counterpart) != isHTMLTemplate(name) {
+			names = append(names, counterpart)
+		}
+	}
+
+	var text, html string
+	for _, name := range names {
+		body, isHTML, err := set.execute(name, data)
+		if err != nil {
+			return "", "", err
+		}
+		if isHTML {
+			html = string(body)
+		} else {
+			text = string(body)
+		}
+	}
+	return text, html, nil
+}
+
+func (set *templateSet) counterpart(name string) (string, bool) {
+	base := strings.TrimSuffix(name, path.Ext(name))
+	if isHTMLTemplate(name) {
+		return base + ".txt", set.text.Lookup(base+".txt") != nil
+	}
+	for _, ext := range []string{".html", ".htm"} {
+		if set.html.Lookup(base+ext) != nil {
+			return base + ext, true
+		}
+	}
+	return "", false
+}
+
+// templateLoader overlays the embedded templates with the files in the templates directory.
+// With reloading enabled, the templates are parsed again whenever the files in the directory change.
+type templateLoader struct {
+	embedded  fs.FS
+	dir       string
+	reload    bool
+	inlineCSS bool
+
+	mu          sync.Mutex
+	set         *templateSet
+	fingerprint string
+}
+
+func newTemplateLoader(embedded fs.FS, dir string, reload, inlineCSS bool) (*templateLoader, error) {
+	loader := &templateLoader{embedded: embedded, dir: dir, reload: reload, inlineCSS: inlineCSS}
+	if _, err := loader.load(); err != nil {
+		return nil, err
+	}
+	return loader, nil
+}
+
+// load returns the parsed templates, parsing them only when they're not up to date.
+func (loader *templateLoader) load() (*templateSet, error) {
+	loader.mu.Lock()
+	defer loader.mu.Unlock()
+
+	if loader.set != nil && !loader.reload {
+		return loader.set, nil
+	}
+
+	var fingerprint string
+	if loader.dir != "" {
+		var err error
+		if fingerprint, err = dirFingerprint(loader.dir); err != nil {
+			return nil, err
+		}
+	}
+	if loader.set != nil && fingerprint == loader.fingerprint {
+		return loader.set, nil
+	}
+
+	set, err := parseTemplates(templateDirs(loader.embedded, loader.dir)...)
+	if err != nil {
+		return nil, err
+	}
+	set.inlineCSS = loader.inlineCSS
+	loader.set, loader.fingerprint = set, fingerprint
+	return set, nil
+}
+
+// templateDirs returns the embedded templates overlaid with the templates directory, when there is one.
+func templateDirs(embedded fs.FS, dir string) []fs.FS {
+	dirs := []fs.FS{embedded}
+	if dir != "" {
+		dirs = append(dirs, os.DirFS(dir))
+	}
+	return dirs
+}
+
+// dirFingerprint changes whenever a template file in the directory is added, removed or modified.
+func dirFingerprint(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	fingerprint := &strings.Builder{}
+	for _, entry := range entries {
+		if entry.IsDir() || !isTemplateFile(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(fingerprint, "%s:%d:%d;", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return fingerprint.String(), nil
+}
+
+// renderHeader renders a subject or sender name template, the fallback is used when there is no template.
+// Line breaks are replaced with spaces in both, because header values have to fit on a single line.
+func renderHeader(text, fallback string, data any) (string, error) {
+	if text == "" {
+		return singleLine(fallback), nil
+	}
+	tmpl, err := parseHeader(text)
+	if err != nil {
+		return "", err
+	}
+	buf := &bytes.Buffer{}
+	if err = tmpl.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return singleLine(buf.String()), nil
+}
+
+// singleLine collapses the whitespace, including line breaks, into single spaces.
+func singleLine(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+func parseHeader(text string) (*texttemplate.Template, error) {
+	return texttemplate.New("").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// validateHeaders checks the syntax of the subject and sender name templates of the form.
+func validateHeaders(form *config.Form) error {
+	headers := map[string]string{
+		"EMAIL_SUBJECT":          form.EmailSubject,
+		"EMAIL_FROM_NAME":        form.EmailFromName,
+		"CONFIRMATION_SUBJECT":   form.ConfirmationSubject,
+		"CONFIRMATION_FROM_NAME": form.ConfirmationFromName,
+	}
+	for lang, text := range form.ConfirmationSubjects {
+		headers["CONFIRMATION_SUBJECTS '"+lang+"'"] = text
+	}
+	for name, text := range headers {
+		if _, err := parseHeader(text); err != nil {
+			return fmt.Errorf("invalid %s value: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func isTemplateFile(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, templateExt := range templateExts {
+		if ext == templateExt {
+			return true
+		}
+	}
+	return false
+}
+
+func isHTMLTemplate(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".html" || ext == ".htm"
+}
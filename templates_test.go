@@ -0,0 +1,65 @@
+package sail
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseTemplatesSkipsOtherFiles(t *testing.T) {
+	embedded := fstest.MapFS{
+		"email.html": {Data: []byte("<p>{{ .FORM_NAME }}</p>")},
+		"email.txt":  {Data: []byte("{{ .FORM_NAME }}")},
+	}
+	dir := fstest.MapFS{
+		"email.txt":     {Data: []byte("Custom {{ .FORM_NAME }}")},
+		"notes.tmpl":    {Data: []byte("{{ .FORM_MESSAGE }}")},
+		"page.HTM":      {Data: []byte("<p>{{ .FORM_NAME }}</p>")},
+		"logo.png":      {Data: []byte("\x89PNG {{")},
+		"email.txt~":    {Data: []byte("{{ end }}")},
+		".DS_Store":     {Data: []byte("{{")},
+		"README":        {Data: []byte("{{ .Missing")},
+		"drafts/a.html": {Data: []byte("{{")},
+	}
+	set, err := parseTemplates(embedded, dir)
+	if err != nil {
+		t.Fatalf("parseTemplates() = %v", err)
+	}
+	for name, want := range map[string]bool{
+		"email.html": true, "email.txt": true, "notes.tmpl": true, "page.HTM": true,
+		"logo.png": false, "email.txt~": false, ".DS_Store": false, "README": false,
+	} {
+		if set.has(name) != want {
+			t.Errorf("has(%q) = %v, want %v", name, !want, want)
+		}
+	}
+	body, _, err := set.execute("email.txt", map[string]any{"FORM_NAME": "Bob"})
+	if err != nil || string(body) != "Custom Bob" {
+		t.Errorf("execute() = %q, %v, the directory should replace the embedded template", body, err)
+	}
+}
+
+func TestDirFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("email.html", "<p>Hi</p>")
+	before, err := dirFingerprint(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write(".email.html.swp", "swap")
+	if after, _ := dirFingerprint(dir); after != before {
+		t.Error("other files should not change the fingerprint")
+	}
+	write("email.txt", "Hi")
+	if after, _ := dirFingerprint(dir); after == before {
+		t.Error("a new template should change the fingerprint")
+	}
+}
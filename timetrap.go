@@ -0,0 +1,155 @@
+package sail
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/demianbucik/sail/config"
+	"github.com/demianbucik/sail/utils"
+)
+
+// timeTrapField carries the token the form got when it was rendered.
+const timeTrapField = "form-token"
+
+// timeTrapPath is the last segment of the URL that issues tokens, for example ".../f/sales/token".
+const timeTrapPath = "token"
+
+// clockSkew is how far in the future a token may be issued, in case several instances disagree on the time.
+const clockSkew = 5 * time.Second
+
+// TimeTrapToken returns a token to embed in the form with the given ID as a hidden "form-token" field,
+// for forms rendered by a Go server. Sail has to be initialized and the form needs TIME_TRAP_SECRET.
+func TimeTrapToken(formId string) (string, error) {
+	serviceMu.RLock()
+	s := service
+	serviceMu.RUnlock()
+	if s == nil {
+		return "", errors.New("sail is not initialized")
+	}
+	formConf, ok := s.env.Form(formId)
+	if !ok {
+		return "", fmt.Errorf("unknown form '%s'", formId)
+	}
+	if !formConf.TimeTrapEnabled() {
+		return "", fmt.Errorf("form '%s' has no TIME_TRAP_SECRET", formId)
+	}
+	return newTimeTrapToken(formConf, time.Now()), nil
+}
+
+// newTimeTrapToken signs the render time in milliseconds together with the form ID,
+// so that a token of one form can't be used for another.
+func newTimeTrapToken(formConf *config.Form, renderedAt time.Time) string {
+	timestamp := strconv.FormatInt(renderedAt.UnixMilli(), 10)
+	return timestamp + "." + timeTrapSignature(formConf, timestamp)
+}
+
+func timeTrapSignature(formConf *config.Form, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(formConf.TimeTrapSecret))
+	mac.Write([]byte(formConf.ID + "\n" + timestamp))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (service *sailService) checkTimeTrap(formConf *config.Form, form *EmailForm) error {
+	if !formConf.TimeTrapEnabled() {
+		return nil
+	}
+	if form.TimeTrapToken == "" {
+		return errors.New("token is missing")
+	}
+	timestamp, signature, ok := strings.Cut(form.TimeTrapToken, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(timeTrapSignature(formConf, timestamp))) {
+		return fmt.Errorf("invalid token '%s'", form.TimeTrapToken)
+	}
+	millis, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid token '%s'", form.TimeTrapToken)
+	}
+
+	fillTime := form.SubmittedAt.Sub(time.UnixMilli(millis))
+	switch {
+	case fillTime < -clockSkew:
+		return fmt.Errorf("token is issued in the future, %s from now", (-fillTime).Round(time.Millisecond))
+	case fillTime < formConf.MinFillTime():
+		return fmt.Errorf("form filled in %s, faster than %s", fillTime.Round(time.Millisecond), formConf.MinFillTime())
+	case fillTime > formConf.MaxFillTime():
+		return fmt.Errorf("token expired %s ago", (fillTime - formConf.MaxFillTime()).Round(time.Second))
+	}
+
+	// The token is reserved right away, so that concurrent submissions with the same token can't both pass.
+	// When the store fails, the token is accepted, as with the duplicate spam rule.
+	used, err := service.spamHashes.Record(timeTrapKey(form.TimeTrapToken), timeTrapWindow(formConf))
+	if err != nil {
+		log.WithError(err).WithField("submissionId", form.SubmissionId).Warn("Spam hash store failed")
+	} else if used {
+		return errors.New("token is already used")
+	}
+	return nil
+}
+
+// releaseTimeTrapToken frees the token reserved by checkTimeTrap when sending fails,
+// so that the visitor can send the form again.
+func (service *sailService) releaseTimeTrapToken(formConf *config.Form, form *EmailForm) {
+	if !formConf.TimeTrapEnabled() {
+		return
+	}
+	if err := service.spamHashes.Forget(timeTrapKey(form.TimeTrapToken)); err != nil {
+		log.WithError(err).WithField("submissionId", form.SubmissionId).Warn("Spam hash store failed")
+	}
+}
+
+func timeTrapKey(token string) string {
+	return "time-trap:" + token
+}
+
+// timeTrapWindow is how long a used token has to be remembered, it expires after that.
+func timeTrapWindow(formConf *config.Form) time.Duration {
+	return formConf.MaxFillTime() + clockSkew
+}
+
+func isTimeTrapRequest(request *http.Request) bool {
+	return request.Method == http.MethodGet && strings.HasSuffix(strings.TrimRight(request.URL.Path, "/"), "/"+timeTrapPath)
+}
+
+// serveTimeTrapToken issues a token for the form in the path, forms rendered by JavaScript fetch it
+// when the page loads and submit it with the form.
+func (service *sailService) serveTimeTrapToken(writer http.ResponseWriter, request *http.Request) {
+	reqCtx := request.Context().Value(utils.RequestCtxKey).(*utils.RequestContext)
+
+	formPath := strings.TrimSuffix(strings.TrimRight(request.URL.Path, "/"), "/"+timeTrapPath)
+	formId := formIdFromPath(formPath)
+	if formId == "" {
+		formId = request.URL.Query().Get(formIdField)
+	}
+	reqCtx.LogEntry = reqCtx.LogEntry.WithField("formId", formId)
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("Cache-Control", "no-store")
+
+	formConf, ok := service.env.Form(formId)
+	if !ok || !formConf.TimeTrapEnabled() {
+		reqCtx.RequestLog.Finalize()
+		reqCtx.LogEntry.Info("Token rejected - unknown form or time trap disabled")
+
+		writer.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(writer).Encode(map[string]string{"status": "error", "message": "Unknown form"})
+		return
+	}
+
+	reqCtx.RequestLog.Finalize()
+	reqCtx.LogEntry.Debug("Token issued")
+	_ = json.NewEncoder(writer).Encode(map[string]string{
+		"status": "ok",
+		"field":  timeTrapField,
+		"token":  newTimeTrapToken(formConf, time.Now()),
+	})
+}
@@ -0,0 +1,154 @@
+package sail
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/demianbucik/sail/spam"
+)
+
+const timeTrapSettings = `
+CONFIRMATION_MODE: "off"
+TIME_TRAP_SECRET: "0123456789abcdef"
+TIME_TRAP_MIN_FILL_TIME: "3s"
+TIME_TRAP_MAX_FILL_TIME: "1h"
+FORMS: |
+  - ID: quote
+  - ID: other
+    TIME_TRAP_SECRET: "fedcba9876543210"
+`
+
+func TestCheckTimeTrap(t *testing.T) {
+	service, _ := newTestService(t, timeTrapSettings)
+	quote, _ := service.env.Form("quote")
+	other, _ := service.env.Form("other")
+	defaultForm, _ := service.env.Form("")
+	submittedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	valid := newTimeTrapToken(quote, submittedAt.Add(-time.Minute))
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{"valid", valid, ""},
+		{"at the min fill time", newTimeTrapToken(quote, submittedAt.Add(-3*time.Second)), ""},
+		{"at the max fill time", newTimeTrapToken(quote, submittedAt.Add(-time.Hour)), ""},
+		{"missing", "", "token is missing"},
+		{"malformed", "1700000000000", "invalid token"},
+		{"tampered timestamp", "1" + valid, "invalid token"},
+		{"tampered signature", valid + "x", "invalid token"},
+		{"not a timestamp", "soon." + timeTrapSignature(quote, "soon"), "invalid token"},
+		{"token of the default form", newTimeTrapToken(defaultForm, submittedAt.Add(-time.Minute)), "invalid token"},
+		{"token of another form", newTimeTrapToken(other, submittedAt.Add(-time.Minute)), "invalid token"},
+		{"too fast", newTimeTrapToken(quote, submittedAt.Add(-2*time.Second)), "faster than 3s"},
+		{"within the clock skew", newTimeTrapToken(quote, submittedAt.Add(clockSkew)), "faster than 3s"},
+		{"issued in the future", newTimeTrapToken(quote, submittedAt.Add(clockSkew+time.Second)), "issued in the future"},
+		{"expired", newTimeTrapToken(quote, submittedAt.Add(-time.Hour-time.Minute)), "token expired 1m0s ago"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service.spamHashes = spam.NewMemoryStore()
+			err := service.checkTimeTrap(quote, &EmailForm{TimeTrapToken: tt.token, SubmittedAt: submittedAt})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkTimeTrap() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkTimeTrap() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckTimeTrapReservesToken(t *testing.T) {
+	service, _ := newTestService(t, timeTrapSettings)
+	quote, _ := service.env.Form("quote")
+	form := &EmailForm{TimeTrapToken: newTimeTrapToken(quote, time.Now().Add(-time.Minute)), SubmittedAt: time.Now()}
+
+	if err := service.checkTimeTrap(quote, form); err != nil {
+		t.Fatalf("checkTimeTrap() = %v", err)
+	}
+	// A concurrent submission with the same token is rejected before the first one is delivered.
+	if err := service.checkTimeTrap(quote, form); err == nil || err.Error() != "token is already used" {
+		t.Errorf("checkTimeTrap() of the reserved token = %v", err)
+	}
+	service.releaseTimeTrapToken(quote, form)
+	if err := service.checkTimeTrap(quote, form); err != nil {
+		t.Errorf("checkTimeTrap() of the released token = %v", err)
+	}
+}
+
+func TestTimeTrapSubmissions(t *testing.T) {
+	service, sent := newTestService(t, timeTrapSettings)
+	quote, _ := service.env.Form("quote")
+	values := url.Values{
+		"form-id":    {"quote"},
+		"name":       {"Bob"},
+		"email":      {"bob@example.com"},
+		"subject":    {"Hi"},
+		"message":    {"Hello"},
+		"form-token": {newTimeTrapToken(quote, time.Now().Add(-time.Minute))},
+	}.Encode()
+
+	// Sending fails, the token is released so the visitor can send the form again.
+	sent.fail(errors.New("connection refused"))
+	if resp := submit(service, values); resp.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, body %s", resp.Code, resp.Body)
+	}
+	sent.fail(nil)
+	if resp := submit(service, values); resp.Code != http.StatusOK {
+		t.Fatalf("status of the second attempt = %d, body %s", resp.Code, resp.Body)
+	}
+	if resp := submit(service, values); resp.Code != http.StatusForbidden {
+		t.Errorf("status of the reused token = %d, want %d", resp.Code, http.StatusForbidden)
+	}
+	if sent.count() != 1 {
+		t.Errorf("sent %d emails, want 1", sent.count())
+	}
+}
+
+func TestServeTimeTrapToken(t *testing.T) {
+	service, _ := newTestService(t, timeTrapSettings)
+	quote, _ := service.env.Form("quote")
+
+	tests := []struct {
+		path     string
+		wantCode int
+	}{
+		{"/send-email/f/quote/token", http.StatusOK},
+		{"/send-email/token/?form-id=quote", http.StatusOK},
+		{"/send-email/f/unknown/token", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp := serve(service, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if resp.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", resp.Code, tt.wantCode)
+			}
+			if resp.Code != http.StatusOK {
+				return
+			}
+			var body map[string]string
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body["field"] != timeTrapField || resp.Header().Get("Cache-Control") != "no-store" {
+				t.Errorf("unexpected response %v", body)
+			}
+			// The token is signed for the form, it's only too fresh to be submitted yet.
+			form := &EmailForm{TimeTrapToken: body["token"], SubmittedAt: time.Now()}
+			if err := service.checkTimeTrap(quote, form); err == nil || !strings.Contains(err.Error(), "faster than") {
+				t.Errorf("checkTimeTrap() = %v", err)
+			}
+		})
+	}
+}
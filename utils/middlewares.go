@@ -2,8 +2,10 @@ package utils
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"runtime/debug"
+	"strings"
 	"time"
 
 	"github.com/apex/log"
@@ -47,6 +49,25 @@ func (reqLog *HttpRequestLog) Finalize() {
 	reqLog.Latency = time.Since(reqLog.Timestamp).String()
 }
 
+// ClientIp returns the IP address of the client. Behind trusted proxies, it's the address the outermost of them
+// appended to X-Forwarded-For, the addresses before it are sent by the client and can be forged.
+// Otherwise, and when the header doesn't have a valid address from the proxies, it's the address of the connection.
+func ClientIp(request *http.Request, trustedProxies int) string {
+	if trustedProxies > 0 {
+		hops := strings.Split(strings.Join(request.Header.Values("X-Forwarded-For"), ","), ",")
+		if len(hops) >= trustedProxies {
+			if ip := strings.TrimSpace(hops[len(hops)-trustedProxies]); net.ParseIP(ip) != nil {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
+
 func LogAndRecoverMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	fn := func(writer http.ResponseWriter, request *http.Request) {
 		reqLog := &HttpRequestLog{